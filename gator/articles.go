@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/sushiqiren/gator/internal/content"
+	"github.com/sushiqiren/gator/internal/database"
+	"golang.org/x/net/html/charset"
+)
+
+// fetchArticle downloads a post's web page and extracts its main content as sanitized HTML
+func (f *fetcher) fetchArticle(ctx context.Context, pageURL string) (string, error) {
+	res, err := f.get(ctx, pageURL, "text/html, application/xhtml+xml;q=0.9, */*;q=0.1")
+	if err != nil {
+		return "", err
+	}
+
+	// Decode the page using the charset from the header or the page's <meta> tag
+	reader, err := charset.NewReader(bytes.NewReader(res.Body), res.Header.Get("Content-Type"))
+	if err != nil {
+		return "", fmt.Errorf("error decoding page: %v", err)
+	}
+
+	base, err := url.Parse(res.FinalURL)
+	if err != nil {
+		return "", err
+	}
+	article, err := content.ExtractArticle(reader, base)
+	if err != nil {
+		return "", err
+	}
+	return cleanText(article), nil
+}
+
+// storeFullContent fetches the full article behind a post and saves it with the post
+func storeFullContent(ctx context.Context, s *state, post database.Post) error {
+	article, err := s.fetcher.fetchArticle(ctx, post.Url)
+	if err != nil {
+		return fmt.Errorf("error fetching full content: %v", err)
+	}
+
+	err = s.db.SetPostContent(ctx, database.SetPostContentParams{
+		ID:      post.ID,
+		Content: nullString(article),
+	})
+	if err != nil {
+		return fmt.Errorf("error storing full content: %v", err)
+	}
+	return nil
+}
+
+func handlerFeedFullText(s *state, cmd command) error {
+	feedUrl := cmd.args[0]
+	enabled := cmd.args[1] == "on"
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), feedUrl)
+	if err != nil {
+		return fmt.Errorf("error getting feed by URL: %v", err)
+	}
+
+	err = s.db.SetFeedFetchFullContent(context.Background(), database.SetFeedFetchFullContentParams{
+		ID:               feed.ID,
+		FetchFullContent: enabled,
+	})
+	if err != nil {
+		return fmt.Errorf("error updating feed: %v", err)
+	}
+
+	fmt.Printf("Full content fetching for %s turned %s\n", feed.Url, cmd.args[1])
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sushiqiren/gator/internal/database"
+	"github.com/sushiqiren/gator/internal/store"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+// sessionLifetime is how long a login lasts before the password is asked for
+// again
+const sessionLifetime = 30 * 24 * time.Hour
+
+// startSession creates a session for the user and saves its token in the
+// config file as the current login. Only a hash of the token is stored in the
+// database, so reading the sessions table does not let anyone log in.
+func startSession(ctx context.Context, s *state, user database.User) error {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("error creating session token: %v", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := s.db.DeleteExpiredSessions(ctx); err != nil {
+		return fmt.Errorf("error deleting expired sessions: %v", err)
+	}
+	err := s.db.CreateSession(ctx, database.CreateSessionParams{
+		TokenHash:       hashToken(token),
+		UserID:          user.ID,
+		LifetimeSeconds: sessionLifetime.Seconds(),
+	})
+	if err != nil {
+		return fmt.Errorf("error creating session: %v", err)
+	}
+	return s.cfg.SetSession(user.Name, token)
+}
+
+// checkSession makes sure the config holds a valid session of the user.
+// Users without a password need no session. The database leaves out expired
+// sessions, judged by its own clock, which is the one that set the expiry.
+func checkSession(ctx context.Context, s *state, user database.User) error {
+	if !user.PasswordHash.Valid {
+		return nil
+	}
+	expired := fmt.Errorf("not logged in as %s or the session has expired: run \"gator login %s\"", user.Name, user.Name)
+	if s.cfg.SessionToken == "" {
+		return expired
+	}
+	session, err := s.db.GetSession(ctx, hashToken(s.cfg.SessionToken))
+	if err == sql.ErrNoRows {
+		return expired
+	} else if err != nil {
+		return fmt.Errorf("error getting session: %v", err)
+	}
+	if session.UserID != user.ID {
+		return expired
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashPassword returns the bcrypt hash of a password, or NULL for an empty
+// password
+func hashPassword(password string) (sql.NullString, error) {
+	if password == "" {
+		return sql.NullString{}, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("error hashing password: %v", err)
+	}
+	return sql.NullString{String: string(hash), Valid: true}, nil
+}
+
+// checkPassword asks for the user's password and compares it with the stored
+// hash
+func checkPassword(user database.User, prompt string) error {
+	password, err := promptPassword(prompt)
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("no password given")
+	}
+	if err != nil {
+		return fmt.Errorf("error reading password: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password)) != nil {
+		return fmt.Errorf("incorrect password")
+	}
+	return nil
+}
+
+// readNewPassword asks for a new password, twice when stdin is a terminal.
+// An empty answer means no password.
+func readNewPassword(prompt string) (string, error) {
+	password, err := promptPassword(prompt + " (leave empty for none)")
+	if errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("no password given (press Enter for none)")
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	if password == "" || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return password, nil
+	}
+
+	again, err := promptPassword("Repeat the password")
+	if err != nil {
+		return "", fmt.Errorf("error reading password: %v", err)
+	}
+	if again != password {
+		return "", fmt.Errorf("the passwords do not match")
+	}
+	return password, nil
+}
+
+func handlerLogout(s *state, cmd command) error {
+	if s.cfg.SessionToken != "" {
+		if err := s.db.DeleteSession(context.Background(), hashToken(s.cfg.SessionToken)); err != nil {
+			return fmt.Errorf("error ending session: %v", err)
+		}
+	}
+	if err := s.cfg.SetSession("", ""); err != nil {
+		return err
+	}
+	fmt.Println("Logged out")
+	return nil
+}
+
+// handlerPasswd sets, changes or removes the password of the current user.
+// Other sessions of the user end, the current one is replaced.
+func handlerPasswd(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+	if user.PasswordHash.Valid {
+		if err := checkPassword(user, "Current password"); err != nil {
+			return err
+		}
+	}
+	password, err := readNewPassword("New password")
+	if err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.InTx(ctx, func(q store.Store) error {
+		err := q.SetUserPassword(ctx, database.SetUserPasswordParams{
+			ID:           user.ID,
+			PasswordHash: hash,
+			UpdatedAt:    time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("error setting password: %v", err)
+		}
+		if err := q.DeleteUserSessions(ctx, user.ID); err != nil {
+			return fmt.Errorf("error ending sessions: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := startSession(ctx, s, user); err != nil {
+		return err
+	}
+
+	if password == "" {
+		fmt.Printf("Removed the password of %s\n", user.Name)
+	} else {
+		fmt.Printf("Changed the password of %s\n", user.Name)
+	}
+	return nil
+}
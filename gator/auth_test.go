@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sushiqiren/gator/internal/config"
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+// setPassword gives a user a password, as passwd would
+func setPassword(t *testing.T, s *state, name, password string) database.User {
+	t.Helper()
+	ctx := context.Background()
+	user, err := s.db.GetUserByName(ctx, name)
+	if err != nil {
+		t.Fatalf("GetUserByName: %v", err)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	err = s.db.SetUserPassword(ctx, database.SetUserPasswordParams{ID: user.ID, PasswordHash: hash, UpdatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("SetUserPassword: %v", err)
+	}
+	user.PasswordHash = hash
+	return user
+}
+
+func TestHashPassword(t *testing.T) {
+	empty, err := hashPassword("")
+	if err != nil || empty.Valid {
+		t.Errorf("hashPassword(\"\") = %v, %v, want NULL", empty, err)
+	}
+	hash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatalf("hashPassword: %v", err)
+	}
+	if !hash.Valid || strings.Contains(hash.String, "secret") {
+		t.Errorf("hashPassword(\"secret\") = %q, want a bcrypt hash", hash.String)
+	}
+}
+
+func TestLoginSavesSession(t *testing.T) {
+	s, cmds := newTestState(t)
+	mustRun(t, s, cmds, "register", "alice", "--no-password")
+
+	saved, err := config.Read(s.cfg.File(), "")
+	if err != nil {
+		t.Fatalf("error reading config: %v", err)
+	}
+	if saved.CurrentUserName != "alice" || saved.SessionToken == "" {
+		t.Errorf("config has user %q and token %q, want alice with a token", saved.CurrentUserName, saved.SessionToken)
+	}
+	if _, err := s.db.GetSession(context.Background(), hashToken(saved.SessionToken)); err != nil {
+		t.Errorf("GetSession: %v", err)
+	}
+
+	mustRun(t, s, cmds, "logout")
+	if s.cfg.CurrentUserName != "" || s.cfg.SessionToken != "" {
+		t.Errorf("config has user %q and token %q after logout", s.cfg.CurrentUserName, s.cfg.SessionToken)
+	}
+	if _, err := s.db.GetSession(context.Background(), hashToken(saved.SessionToken)); err == nil {
+		t.Errorf("the session survived logout")
+	}
+}
+
+func TestPasswordUserNeedsSession(t *testing.T) {
+	ctx := context.Background()
+	s, cmds := newTestState(t)
+	mustRun(t, s, cmds, "register", "alice", "--no-password")
+	mustRun(t, s, cmds, "register", "bob", "--no-password")
+	alice := setPassword(t, s, "alice", "secret")
+
+	tests := []struct {
+		name    string
+		setup   func()
+		wantErr bool
+	}{
+		{
+			name:  "passwordless user",
+			setup: func() {},
+		},
+		{
+			name:    "bob's session for alice",
+			setup:   func() { s.cfg.CurrentUserName = "alice" },
+			wantErr: true,
+		},
+		{
+			name:    "no session",
+			setup:   func() { s.cfg.SessionToken = "" },
+			wantErr: true,
+		},
+		{
+			name: "alice's session",
+			setup: func() {
+				if err := startSession(ctx, s, alice); err != nil {
+					t.Fatalf("startSession: %v", err)
+				}
+			},
+		},
+		{
+			name: "expired session",
+			setup: func() {
+				s.cfg.SessionToken = "expired"
+				err := s.db.CreateSession(ctx, database.CreateSessionParams{
+					TokenHash:       hashToken("expired"),
+					UserID:          alice.ID,
+					LifetimeSeconds: -1,
+				})
+				if err != nil {
+					t.Fatalf("CreateSession: %v", err)
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			_, err := runCommand(s, cmds, "following")
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "not logged in as alice")) {
+				t.Errorf("got error %v, want a login error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("following: %v", err)
+			}
+		})
+	}
+}
+
+func TestResetNeedsSession(t *testing.T) {
+	ctx := context.Background()
+	s, cmds := newTestState(t)
+	mustRun(t, s, cmds, "register", "alice", "--no-password")
+	alice := setPassword(t, s, "alice", "secret")
+	s.cfg.SessionToken = ""
+
+	if _, err := runCommand(s, cmds, "reset"); err == nil {
+		t.Fatalf("reset ran without a session of alice")
+	}
+	if _, err := s.db.GetUserByName(ctx, "alice"); err != nil {
+		t.Fatalf("alice was deleted: %v", err)
+	}
+
+	if err := startSession(ctx, s, alice); err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	mustRun(t, s, cmds, "reset")
+	if users, err := s.db.GetUsers(ctx); err != nil || len(users) != 0 {
+		t.Errorf("GetUsers after reset = %v, %v, want no users", users, err)
+	}
+}
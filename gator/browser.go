@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+// browserCommand returns the program and arguments that open a URL, using the
+// configured browser, then $BROWSER, then the platform's default handler
+func browserCommand(s *state, link string) ([]string, error) {
+	browser := strings.TrimSpace(s.cfg.Browser)
+	if browser == "" {
+		browser = strings.TrimSpace(os.Getenv("BROWSER"))
+	}
+	if browser != "" {
+		args := strings.Fields(browser)
+		substituted := false
+		for i, arg := range args {
+			if strings.Contains(arg, "%s") {
+				args[i] = strings.ReplaceAll(arg, "%s", link)
+				substituted = true
+			}
+		}
+		if !substituted {
+			args = append(args, link)
+		}
+		return args, nil
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open", link}, nil
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler", link}, nil
+	default:
+		if _, err := exec.LookPath("xdg-open"); err != nil {
+			return nil, fmt.Errorf("xdg-open not found: set \"browser\" in the config file")
+		}
+		return []string{"xdg-open", link}, nil
+	}
+}
+
+// openURL launches the browser on a link without waiting for it to exit
+func openURL(s *state, link string) error {
+	if !isWebURL(link) {
+		return fmt.Errorf("refusing to open %q: only http and https links are supported", link)
+	}
+	args, err := browserCommand(s, link)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting browser: %v", err)
+	}
+	// Reap the process in the background so long-running modes leave no zombies
+	go cmd.Wait()
+	return nil
+}
+
+// isWebURL reports whether a link is an absolute http or https URL, so feed
+// content cannot make gator launch local files or other protocol handlers
+func isWebURL(link string) bool {
+	lower := strings.ToLower(link)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// openPost opens a post's link in the browser and marks the post as read
+func openPost(ctx context.Context, s *state, user database.User, post database.GetPostsByIDPrefixRow) error {
+	if err := openURL(s, post.Url); err != nil {
+		return err
+	}
+	err := s.db.MarkPostRead(ctx, database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error marking post as read: %v", err)
+	}
+	return nil
+}
+
+func handlerOpen(s *state, cmd command, user database.User) error {
+
+	post, err := resolvePost(context.Background(), s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	if err := openPost(context.Background(), s, user, post); err != nil {
+		return err
+	}
+
+	fmt.Printf("Opened: %s\n", post.Url)
+	return nil
+}
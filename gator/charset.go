@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var (
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+	// xmlDeclEncoding matches the encoding attribute of an XML declaration
+	xmlDeclEncoding = regexp.MustCompile(`^(<\?xml[^>]*?\sencoding\s*=\s*)(["'])[^"']*(["'])`)
+)
+
+// isUTF8Label reports whether a charset label names UTF-8 or one of its subsets
+func isUTF8Label(label string) bool {
+	switch strings.ToLower(strings.TrimSpace(label)) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return true
+	}
+	return false
+}
+
+// transcodeFeed converts a feed body to UTF-8. The charset from the Content-Type
+// header takes precedence over the XML declaration, as required by RFC 7303.
+// Whenever the header names a charset, the declaration is rewritten to say
+// UTF-8 so the XML decoder does not convert the body a second time.
+func transcodeFeed(body []byte, contentType string) ([]byte, error) {
+	body = bytes.TrimPrefix(body, utf8BOM)
+
+	label := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	if label == "" {
+		return body, nil
+	}
+	if isUTF8Label(label) {
+		return xmlDeclEncoding.ReplaceAll(body, []byte("${1}${2}UTF-8${3}")), nil
+	}
+
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %v", label, err)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s feed: %v", label, err)
+	}
+
+	decoded = bytes.TrimPrefix(decoded, utf8BOM)
+	return xmlDeclEncoding.ReplaceAll(decoded, []byte("${1}${2}UTF-8${3}")), nil
+}
+
+// newFeedDecoder returns an XML decoder that honors the encoding named in the XML declaration
+func newFeedDecoder(body []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder
+}
+
+// cleanText makes a string safe to store in PostgreSQL text columns by
+// replacing invalid UTF-8 sequences and dropping NUL bytes
+func cleanText(s string) string {
+	s = strings.ToValidUTF8(s, "�")
+	return strings.ReplaceAll(s, "\x00", "")
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestTranscodeFeed(t *testing.T) {
+	feed := func(decl, title string) []byte {
+		return []byte(decl + `<rss version="2.0"><channel><title>` + title + `</title></channel></rss>`)
+	}
+	latin1 := `<?xml version="1.0" encoding="iso-8859-1"?>`
+	utf8 := `<?xml version="1.0" encoding="utf-8"?>`
+
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+	}{
+		{"no header, utf-8 declaration", feed(utf8, "café"), "application/rss+xml"},
+		{"no header, latin-1 declaration", feed(latin1, "caf\xe9"), "application/rss+xml"},
+		{"latin-1 header", feed(utf8, "caf\xe9"), "application/rss+xml; charset=iso-8859-1"},
+		{"utf-8 header overrides a latin-1 declaration", feed(latin1, "café"), "application/rss+xml; charset=utf-8"},
+		{"utf-8 header with a bom", append([]byte("\xef\xbb\xbf"), feed(latin1, "café")...), "text/xml; charset=UTF-8"},
+		{"windows-1252 header", feed("", "caf\xe9"), "text/xml; charset=windows-1252"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := transcodeFeed(tt.body, tt.contentType)
+			if err != nil {
+				t.Fatalf("transcodeFeed: %v", err)
+			}
+			feed, warning, err := parseFeed(body)
+			if err != nil {
+				t.Fatalf("parseFeed: %v", err)
+			}
+			if warning != "" {
+				t.Errorf("parseFeed warning: %s", warning)
+			}
+			if got := feed.Channel.Title; got != "café" {
+				t.Errorf("title = %q, want %q", got, "café")
+			}
+		})
+	}
+}
+
+func TestTranscodeFeedUnknownCharset(t *testing.T) {
+	if _, err := transcodeFeed([]byte("<rss/>"), "text/xml; charset=klingon"); err == nil {
+		t.Errorf("transcodeFeed accepted an unknown charset")
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type command struct {
+	name string
+	// args holds the positional arguments, already checked against the spec
+	args []string
+	// flags holds the parsed values of the command's flags
+	flags *flag.FlagSet
+}
+
+// argSpec describes a positional argument of a command
+type argSpec struct {
+	name     string
+	optional bool
+	// choices, when set, lists the only values the argument accepts
+	choices []string
+	// complete names the kind of value offered by shell completion
+	complete string
+}
+
+// commandSpec describes a command: its documentation, the arguments and flags
+// it accepts and the function that runs it
+type commandSpec struct {
+	name        string
+	description string
+	args        []argSpec
+	// flags defines the command's own flags on the given set
+	flags func(fs *flag.FlagSet)
+	// flagCompletions maps flag names to the kind of value they complete
+	flagCompletions map[string]string
+	handler         func(*state, command) error
+	// hidden commands are left out of the help and of completion
+	hidden bool
+	// offline commands run without checking the database schema first
+	offline bool
+	// standalone commands also run without a config file or database
+	standalone bool
+}
+
+type commands struct {
+	specs map[string]commandSpec
+}
+
+func (c *commands) register(spec commandSpec) {
+	c.specs[spec.name] = spec
+}
+
+// options come before the command name because they are needed before any
+// command runs
+type options struct {
+	configPath string
+	profile    string
+}
+
+func optionFlags(fs *flag.FlagSet, opts *options) {
+	fs.StringVar(&opts.configPath, "config", "", "read the configuration from `file` (default $GATOR_CONFIG or $XDG_CONFIG_HOME/gator/config.json)")
+	fs.StringVar(&opts.profile, "profile", "", "use the named `profile` from the config file (default $GATOR_PROFILE or the current profile)")
+}
+
+// parseOptions splits the program arguments into the options and the command
+// with its arguments
+func parseOptions(args []string) (options, []string, error) {
+	var opts options
+	fs := flag.NewFlagSet("gator", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	optionFlags(fs, &opts)
+	if err := fs.Parse(args); err != nil {
+		return opts, nil, err
+	}
+	return opts, fs.Args(), nil
+}
+
+// globalFlags are accepted by every command
+func globalFlags(fs *flag.FlagSet) {
+	output := fs.String("output", "text", "output `format`: text, json, ndjson, csv or a Go template")
+	fs.StringVar(output, "o", "text", "shorthand for --output")
+}
+
+// flagSet builds the flag set holding the command's own and the global flags
+func (spec commandSpec) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if spec.flags != nil {
+		spec.flags(fs)
+	}
+	globalFlags(fs)
+	return fs
+}
+
+// usage returns the one-line synopsis of the command
+func (spec commandSpec) usage() string {
+	parts := []string{"gator", spec.name}
+	for _, arg := range spec.args {
+		name := arg.name
+		if len(arg.choices) > 0 {
+			name = strings.Join(arg.choices, "|")
+		}
+		if arg.optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	parts = append(parts, "[flags]")
+	return strings.Join(parts, " ")
+}
+
+// parse splits the raw arguments into flags and positional arguments and
+// validates them against the spec
+func (spec commandSpec) parse(rawArgs []string) (command, error) {
+	fs := spec.flagSet()
+
+	// The flag package stops at the first positional argument, so keep parsing
+	// after each one to allow flags anywhere on the command line
+	var positional []string
+	args := rawArgs
+	for {
+		if err := fs.Parse(args); err != nil {
+			return command{}, err
+		}
+		consumed := len(args) - len(fs.Args())
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		if consumed > 0 && rawArgs[len(rawArgs)-len(args)-1] == "--" {
+			positional = append(positional, args...)
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	required := 0
+	for _, arg := range spec.args {
+		if !arg.optional {
+			required++
+		}
+	}
+	if len(positional) < required {
+		return command{}, fmt.Errorf("missing %s argument", spec.args[len(positional)].name)
+	}
+	if len(positional) > len(spec.args) {
+		return command{}, fmt.Errorf("unexpected argument %q", positional[len(spec.args)])
+	}
+	for i, value := range positional {
+		arg := spec.args[i]
+		if len(arg.choices) > 0 && !contains(arg.choices, value) {
+			return command{}, fmt.Errorf("invalid %s %q: expected one of %s", arg.name, value, strings.Join(arg.choices, ", "))
+		}
+	}
+
+	return command{name: spec.name, args: positional, flags: fs}, nil
+}
+
+func (c *commands) run(s *state, cmd command) error {
+	spec, exists := c.specs[cmd.name]
+	if !exists {
+		return fmt.Errorf("unknown command: %s (run \"gator help\" for a list of commands)", cmd.name)
+	}
+
+	parsed, err := spec.parse(cmd.args)
+	if errors.Is(err, flag.ErrHelp) {
+		spec.printHelp(os.Stdout)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%v\nusage: %s", err, spec.usage())
+	}
+
+	if !spec.offline {
+		if err := checkSchema(context.Background(), s); err != nil {
+			return err
+		}
+	}
+
+	// The global --output flag applies to every command. Commands run from the
+	// shell get their own output, so the shell's is restored afterwards.
+	out, err := newOutput(parsed.stringFlag("output"), os.Stdout)
+	if err != nil {
+		return err
+	}
+	previous := s.out
+	s.out = out
+	defer func() { s.out = previous }()
+
+	if err := spec.handler(s, parsed); err != nil {
+		return err
+	}
+	return out.flush()
+}
+
+// names returns the registered command names in alphabetical order
+func (c *commands) names() []string {
+	names := make([]string, 0, len(c.specs))
+	for name := range c.specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printHelp lists every command with its description
+func (c *commands) printHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: gator [options] <command> [arguments] [flags]\n\nCommands:\n")
+	for _, name := range c.names() {
+		if spec := c.specs[name]; !spec.hidden {
+			fmt.Fprintf(w, "  %-16s %s\n", name, spec.description)
+		}
+	}
+
+	fs := flag.NewFlagSet("gator", flag.ContinueOnError)
+	optionFlags(fs, &options{})
+	fmt.Fprintf(w, "\nOptions:\n")
+	printFlags(w, fs)
+	fmt.Fprintf(w, "\nRun \"gator help <command>\" or \"gator <command> --help\" for details.\n")
+}
+
+// printHelp describes a single command, its arguments and its flags
+func (spec commandSpec) printHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", spec.usage(), spec.description)
+
+	fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	if spec.flags != nil {
+		spec.flags(fs)
+		fmt.Fprintf(w, "\nFlags:\n")
+		printFlags(w, fs)
+	}
+
+	fs = flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	globalFlags(fs)
+	fmt.Fprintf(w, "\nGlobal flags:\n")
+	printFlags(w, fs)
+}
+
+// printFlags lists the flags of a set, folding one-letter shorthands into the
+// long flag they stand for
+func printFlags(w io.Writer, fs *flag.FlagSet) {
+	const shorthandPrefix = "shorthand for --"
+	shorthands := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if long, ok := strings.CutPrefix(f.Usage, shorthandPrefix); ok {
+			shorthands[long] = f.Name
+		}
+	})
+
+	fs.VisitAll(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Usage, shorthandPrefix) {
+			return
+		}
+		name, usage := flag.UnquoteUsage(f)
+		label := "--" + f.Name
+		if short, ok := shorthands[f.Name]; ok {
+			label = "-" + short + ", " + label
+		}
+		if name != "" {
+			label += " " + name
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			usage += fmt.Sprintf(" (default %q)", f.DefValue)
+		}
+		fmt.Fprintf(w, "  %-28s %s\n", label, usage)
+	})
+}
+
+func (cmd command) stringFlag(name string) string {
+	return cmd.flags.Lookup(name).Value.String()
+}
+
+func (cmd command) boolFlag(name string) bool {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
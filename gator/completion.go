@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sushiqiren/gator/internal/config"
+)
+
+// Kinds of dynamic values that arguments and flags can complete
+const (
+	completeCommand      = "command"
+	completeUser         = "user"
+	completeFeed         = "feed"
+	completeFollowedFeed = "followed-feed"
+	completeOutput       = "output"
+	completeProfile      = "profile"
+)
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// Completion scripts ask the hidden __complete command for candidates, passing
+// the command line up to the cursor, so they never go out of date
+const bashCompletion = `# bash completion for gator
+_gator() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local cur="${line##*[[:space:]]}"
+    local IFS=$'\n'
+    local candidates=($(gator __complete "$line" 2>/dev/null))
+
+    # Bash splits words at colons, so drop the part of a URL already typed
+    if [[ "$cur" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+        local prefix="${cur%"${cur##*:}"}"
+        candidates=("${candidates[@]#"$prefix"}")
+    fi
+    COMPREPLY=("${candidates[@]}")
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator
+_gator() {
+    local line="${(j: :)words[1,CURRENT]}"
+    local -a candidates
+    candidates=("${(@f)$(gator __complete "$line" 2>/dev/null)}")
+    compadd -- ${candidates:#}
+}
+compdef _gator gator
+`
+
+const fishCompletion = `# fish completion for gator
+complete -c gator -f -a '(gator __complete (commandline -cp))'
+`
+
+func handlerCompletion(s *state, cmd command) error {
+	switch cmd.args[0] {
+	case "bash":
+		fmt.Print(bashCompletion)
+	case "zsh":
+		fmt.Print(zshCompletion)
+	case "fish":
+		fmt.Print(fishCompletion)
+	}
+	return nil
+}
+
+// complete returns the candidates for the last word of a partial command line.
+// The first word is the program name; a trailing space starts a new word.
+func (c *commands) complete(s *state, line string) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return nil
+	}
+	if last, _ := utf8.DecodeLastRuneInString(line); unicode.IsSpace(last) {
+		words = append(words, "")
+	}
+	words = skipOptions(words[1:])
+	if len(words) == 0 {
+		return nil
+	}
+	current := words[len(words)-1]
+
+	if len(words) == 1 {
+		return withPrefix(c.completeValue(s, completeCommand), current)
+	}
+	spec, exists := c.specs[words[0]]
+	if !exists {
+		return nil
+	}
+	fs := spec.flagSet()
+
+	// Walk the words before the cursor to find what is being completed
+	position := 0
+	valueOf := ""
+	for _, word := range words[1 : len(words)-1] {
+		if valueOf != "" {
+			valueOf = ""
+			continue
+		}
+		if name, ok := flagName(word); ok {
+			if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && !strings.Contains(word, "=") {
+				valueOf = name
+			}
+			continue
+		}
+		position++
+	}
+
+	switch {
+	case valueOf != "":
+		return withPrefix(c.completeValue(s, spec.flagCompletion(valueOf)), current)
+	case strings.HasPrefix(current, "-"):
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			if len(f.Name) > 1 {
+				flags = append(flags, "--"+f.Name)
+			}
+		})
+		return withPrefix(flags, current)
+	case position < len(spec.args):
+		arg := spec.args[position]
+		if len(arg.choices) > 0 {
+			return withPrefix(arg.choices, current)
+		}
+		return withPrefix(c.completeValue(s, arg.complete), current)
+	}
+	return nil
+}
+
+// skipOptions drops the options that come before the command name, keeping
+// the word being completed
+func skipOptions(words []string) []string {
+	for len(words) > 1 {
+		name, ok := flagName(words[0])
+		if !ok {
+			break
+		}
+		if (name == "config" || name == "profile") && !strings.Contains(words[0], "=") && len(words) > 2 {
+			words = words[1:]
+		}
+		words = words[1:]
+	}
+	return words
+}
+
+// flagCompletion returns the kind of value a flag of the command takes
+func (spec commandSpec) flagCompletion(name string) string {
+	if name == "output" || name == "o" {
+		return completeOutput
+	}
+	return spec.flagCompletions[name]
+}
+
+// completeValue lists the candidates of a kind. Values that need the database
+// are left out when there is no config or the database cannot be reached, so
+// completion never prints errors.
+func (c *commands) completeValue(s *state, kind string) []string {
+	ctx := context.Background()
+	if s.db == nil && kind != completeCommand && kind != completeOutput && kind != completeProfile {
+		return nil
+	}
+	var values []string
+	switch kind {
+	case completeCommand:
+		for _, name := range c.names() {
+			if !c.specs[name].hidden {
+				values = append(values, name)
+			}
+		}
+	case completeOutput:
+		values = append(values, outputFormats...)
+	case completeProfile:
+		profiles, err := config.ListProfiles(s.cfg.File())
+		if err != nil {
+			return nil
+		}
+		for name := range profiles {
+			values = append(values, name)
+		}
+	case completeUser:
+		users, err := s.db.GetUsers(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, user := range users {
+			values = append(values, user.Name)
+		}
+	case completeFeed:
+		feeds, err := s.db.GetFeedsWithUserNames(ctx)
+		if err != nil {
+			return nil
+		}
+		for _, feed := range feeds {
+			values = append(values, feed.Url)
+		}
+	case completeFollowedFeed:
+		user, err := s.db.GetUserByName(ctx, s.cfg.CurrentUserName)
+		if err != nil {
+			return nil
+		}
+		follows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+		if err != nil {
+			return nil
+		}
+		for _, follow := range follows {
+			values = append(values, follow.FeedUrl)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+func handlerComplete(cmds *commands) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		line := ""
+		if len(cmd.args) > 0 {
+			line = cmd.args[0]
+		}
+		for _, candidate := range cmds.complete(s, line) {
+			fmt.Println(candidate)
+		}
+		return nil
+	}
+}
+
+// flagName returns the name of a flag argument such as --feed or -o=json
+func flagName(word string) (string, bool) {
+	if len(word) < 2 || word[0] != '-' || word == "--" {
+		return "", false
+	}
+	name := strings.TrimLeft(word, "-")
+	name, _, _ = strings.Cut(name, "=")
+	return name, true
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func withPrefix(values []string, prefix string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/sushiqiren/gator/internal/config"
+)
+
+const (
+	defaultFetchTimeout   = 30 * time.Second
+	defaultConnectTimeout = 10 * time.Second
+	defaultReadTimeout    = 20 * time.Second
+	defaultMaxBodyBytes   = 10 << 20
+	defaultContactURL     = "https://github.com/sushiqiren/gator"
+)
+
+// feedResponse is the result of fetching a feed
+type feedResponse struct {
+	Feed *RSSFeed
+	// FinalURL is the URL the feed was served from after following redirects
+	FinalURL string
+	// Moved is true when the feed was redirected and every hop was permanent (301 or 308)
+	Moved bool
+	// Warning describes how a malformed feed was recovered, and is empty for well-formed feeds
+	Warning string
+}
+
+// fetchStatusError is returned when a feed server answers with a non-200 status
+type fetchStatusError struct {
+	StatusCode int
+	Status     string
+	// RetryAfter is the delay requested by the server's Retry-After header, or zero
+	RetryAfter time.Duration
+}
+
+func (e *fetchStatusError) Error() string {
+	return fmt.Sprintf("failed to fetch feed: %s", e.Status)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// isPermanentRedirect reports whether a status code marks a permanent move
+func isPermanentRedirect(statusCode int) bool {
+	return statusCode == http.StatusMovedPermanently || statusCode == http.StatusPermanentRedirect
+}
+
+// fetcher is the HTTP client shared by everything that downloads feeds.
+// It reuses connections across requests and enforces the limits from the config file.
+type fetcher struct {
+	client       *http.Client
+	userAgent    string
+	maxBodyBytes int64
+	limiter      *hostLimiter
+	// robots is nil unless robots.txt checking is enabled
+	robots *robotsCache
+}
+
+// fetchedResource is a successfully downloaded HTTP response body
+type fetchedResource struct {
+	Body   []byte
+	Header http.Header
+	// FinalURL is the URL the resource was served from after following redirects
+	FinalURL string
+	// Moved is true when the resource was redirected and every hop was permanent (301 or 308)
+	Moved bool
+}
+
+// newFetcher builds the shared fetch client from the fetch section of the config file
+func newFetcher(cfg config.FetchConfig) (*fetcher, error) {
+	timeout, err := parseConfigDuration("fetch.timeout", cfg.Timeout, defaultFetchTimeout)
+	if err != nil {
+		return nil, err
+	}
+	connectTimeout, err := parseConfigDuration("fetch.connect_timeout", cfg.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	readTimeout, err := parseConfigDuration("fetch.read_timeout", cfg.ReadTimeout, defaultReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	perHostInterval, err := parseConfigDuration("fetch.per_host_interval", cfg.PerHostInterval, defaultPerHostInterval)
+	if err != nil {
+		return nil, err
+	}
+	perHostConcurrency := cfg.PerHostConcurrency
+	if perHostConcurrency <= 0 {
+		perHostConcurrency = defaultPerHostConcurrency
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fetch.proxy: %v", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("invalid fetch.proxy: unsupported scheme %q", proxyURL.Scheme)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		ExpectContinueTimeout: time.Second,
+		// Compression is negotiated by hand so that brotli is supported as well
+		DisableCompression: true,
+	}
+
+	maxBodyBytes := cfg.MaxBodyBytes
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		contactURL := cfg.ContactURL
+		if contactURL == "" {
+			contactURL = defaultContactURL
+		}
+		userAgent = fmt.Sprintf("gator/%s (+%s)", version, contactURL)
+	}
+
+	f := &fetcher{
+		client:       &http.Client{Transport: transport, Timeout: timeout},
+		userAgent:    userAgent,
+		maxBodyBytes: maxBodyBytes,
+		limiter:      newHostLimiter(perHostInterval, perHostConcurrency),
+	}
+	if cfg.RespectRobots {
+		f.robots = newRobotsCache()
+	}
+	return f, nil
+}
+
+// parseConfigDuration parses an optional duration setting, falling back to a default
+func parseConfigDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive duration such as 30s", name, value)
+	}
+	return d, nil
+}
+
+// get downloads a URL while respecting the per-host rate limit and, when enabled, robots.txt
+func (f *fetcher) get(ctx context.Context, rawURL string, accept string) (*fetchedResource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := f.limiter.acquire(ctx, hostKey(u))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if f.robots != nil && !f.robots.allowed(ctx, f, u) {
+		return nil, errRobotsDisallowed
+	}
+
+	return f.do(ctx, rawURL, accept)
+}
+
+// do downloads a URL, decoding compressed responses and enforcing the body size limit.
+// Non-200 responses are returned as *fetchStatusError.
+func (f *fetcher) do(ctx context.Context, rawURL string, accept string) (*fetchedResource, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept-Encoding", "gzip, br")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	// Track whether every redirect on the way to the final URL was permanent.
+	// The client is copied so the shared transport and its connections are reused.
+	redirected := false
+	permanent := true
+	client := *f.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		redirected = true
+		if req.Response == nil || !isPermanentRedirect(req.Response.StatusCode) {
+			permanent = false
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &fetchStatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	var reader io.Reader = resp.Body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing response: %v", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "br":
+		reader = brotli.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	// Read one byte past the limit so oversized bodies can be detected
+	body, err := io.ReadAll(io.LimitReader(reader, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > f.maxBodyBytes {
+		return nil, fmt.Errorf("response body exceeds the maximum size of %d bytes", f.maxBodyBytes)
+	}
+
+	return &fetchedResource{
+		Body:     body,
+		Header:   resp.Header,
+		FinalURL: resp.Request.URL.String(),
+		Moved:    redirected && permanent,
+	}, nil
+}
+
+// fetchFeed downloads and parses an RSS feed
+func (f *fetcher) fetchFeed(ctx context.Context, feedURL string) (*feedResponse, error) {
+	res, err := f.get(ctx, feedURL, "application/rss+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.1")
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert non-UTF-8 feeds before parsing
+	body, err := transcodeFeed(res.Body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	feed, warning, err := parseFeed(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unescape HTML entities in the feed and make sure only clean UTF-8 is stored.
+	// Item descriptions are HTML, which content.Sanitize decodes when it parses
+	// them; unescaping them here as well would turn escaped text into markup.
+	feed.Channel.Title = cleanText(html.UnescapeString(feed.Channel.Title))
+	feed.Channel.Description = cleanText(html.UnescapeString(feed.Channel.Description))
+	feed.Channel.Language = cleanText(feed.Channel.Language)
+	feed.Channel.Generator = cleanText(feed.Channel.Generator)
+	for i := range feed.Channel.Items {
+		item := &feed.Channel.Items[i]
+		item.Title = cleanText(html.UnescapeString(item.Title))
+		item.Description = cleanText(item.Description)
+		item.Link = cleanText(item.Link)
+		item.PubDate = cleanText(item.PubDate)
+		item.Author = cleanText(html.UnescapeString(item.Author))
+		item.Creator = cleanText(html.UnescapeString(item.Creator))
+		for j := range item.Categories {
+			item.Categories[j] = cleanText(html.UnescapeString(item.Categories[j]))
+		}
+		for j := range item.Enclosures {
+			item.Enclosures[j].URL = cleanText(item.Enclosures[j].URL)
+			item.Enclosures[j].Type = cleanText(item.Enclosures[j].Type)
+		}
+	}
+
+	return &feedResponse{
+		Feed:     feed,
+		FinalURL: res.FinalURL,
+		Moved:    res.Moved,
+		Warning:  warning,
+	}, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+const (
+	filterActionInclude = "include"
+	filterActionExclude = "exclude"
+)
+
+var filterFields = []string{"title", "description", "content", "url", "any"}
+
+// postFilter is a compiled user_filters row that can be matched against posts
+type postFilter struct {
+	action  string
+	field   string
+	pattern string
+	re      *regexp.Regexp
+	feedID  uuid.NullUUID
+}
+
+// matches reports whether the filter applies to the given post
+func (f postFilter) matches(post database.Post) bool {
+	if f.feedID.Valid && f.feedID.UUID != post.FeedID {
+		return false
+	}
+
+	var values []string
+	switch f.field {
+	case "title":
+		values = []string{post.Title}
+	case "description":
+		values = []string{post.Description.String}
+	case "content":
+		values = []string{post.Content.String}
+	case "url":
+		values = []string{post.Url}
+	default:
+		values = []string{post.Title, post.Description.String, post.Content.String, post.Url}
+	}
+
+	for _, value := range values {
+		if f.re != nil {
+			if f.re.MatchString(value) {
+				return true
+			}
+		} else if strings.Contains(strings.ToLower(value), strings.ToLower(f.pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// postFilters is the set of filters configured by a single user
+type postFilters []postFilter
+
+// loadPostFilters reads and compiles the filters configured for a user
+func loadPostFilters(ctx context.Context, s *state, userID uuid.UUID) (postFilters, error) {
+	rows, err := s.db.GetUserFiltersForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting filters: %v", err)
+	}
+
+	filters := make(postFilters, 0, len(rows))
+	for _, row := range rows {
+		f := postFilter{
+			action:  row.Action,
+			field:   row.Field,
+			pattern: row.Pattern,
+			feedID:  row.FeedID,
+		}
+		if row.IsRegex {
+			f.re, err = regexp.Compile(row.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("error compiling filter %s: %v", row.ID, err)
+			}
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// apply reports whether a post should be hidden and whether it should be highlighted.
+// Exclude rules win over include rules.
+func (fs postFilters) apply(post database.Post) (hidden bool, highlighted bool) {
+	for _, f := range fs {
+		if !f.matches(post) {
+			continue
+		}
+		switch f.action {
+		case filterActionExclude:
+			return true, false
+		case filterActionInclude:
+			highlighted = true
+		}
+	}
+	return false, highlighted
+}
+
+func handlerAddFilter(s *state, cmd command, user database.User) error {
+	action, field, pattern := cmd.args[0], cmd.args[1], cmd.args[2]
+	isRegex := cmd.boolFlag("regex")
+	feedUrl := cmd.stringFlag("feed")
+
+	if isRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regular expression: %v", err)
+		}
+	}
+
+	// Resolve the optional feed scope
+	feedID := uuid.NullUUID{}
+	if feedUrl != "" {
+		feed, err := s.db.GetFeedByUrl(context.Background(), feedUrl)
+		if err != nil {
+			return fmt.Errorf("error getting feed by URL: %v", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	filter, err := s.db.CreateUserFilter(context.Background(), database.CreateUserFilterParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		Action:    action,
+		Field:     field,
+		Pattern:   pattern,
+		IsRegex:   isRegex,
+		FeedID:    feedID,
+	})
+	if err != nil {
+		return fmt.Errorf("error creating filter: %v", err)
+	}
+
+	fmt.Printf("Filter created: %s\n", filter.ID)
+	return nil
+}
+
+type filterRecord struct {
+	ID      string  `json:"id"`
+	Action  string  `json:"action"`
+	Field   string  `json:"field"`
+	Pattern string  `json:"pattern"`
+	Regex   bool    `json:"regex"`
+	FeedUrl *string `json:"feed_url"`
+}
+
+func handlerFilters(s *state, cmd command, user database.User) error {
+	filters, err := s.db.GetUserFiltersForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting filters: %v", err)
+	}
+
+	for _, filter := range filters {
+		record := filterRecord{
+			ID:      filter.ID.String(),
+			Action:  filter.Action,
+			Field:   filter.Field,
+			Pattern: filter.Pattern,
+			Regex:   filter.IsRegex,
+			FeedUrl: optionalString(filter.FeedUrl),
+		}
+		err := s.out.emit(record, func() {
+			kind := "substring"
+			if filter.IsRegex {
+				kind = "regex"
+			}
+			scope := "all feeds"
+			if filter.FeedUrl.Valid {
+				scope = filter.FeedUrl.String
+			}
+			fmt.Printf("ID: %s\n", filter.ID)
+			fmt.Printf("Rule: %s %s %s %q\n", filter.Action, filter.Field, kind, filter.Pattern)
+			fmt.Printf("Scope: %s\n\n", scope)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handlerRemoveFilter(s *state, cmd command, user database.User) error {
+	filterID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("error parsing filter ID: %v", err)
+	}
+
+	removed, err := s.db.DeleteUserFilter(context.Background(), database.DeleteUserFilterParams{
+		ID:     filterID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error removing filter: %v", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("filter %s does not exist", filterID)
+	}
+
+	fmt.Printf("Removed filter: %s\n", filterID)
+	return nil
+}
@@ -1,73 +1,240 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 )
 
-const configFileName = ".gatorconfig.json"
+const (
+	configFileName       = "config.json"
+	legacyConfigFileName = ".gatorconfig.json"
+)
+
+// DefaultProfile is the name of the profile stored at the top level of the file
+const DefaultProfile = "default"
 
-// Config represents the JSON file structure
+// Config represents the JSON file structure. The embedded Profile holds the
+// settings of the profile in use, which are the top-level settings of the
+// file unless another profile was selected.
 type Config struct {
+	Profile
+	Retention RetentionConfig `json:"retention"`
+	// Browser is the command used to open links, e.g. "firefox --new-tab".
+	// A %s argument is replaced by the URL, otherwise the URL is appended.
+	Browser string `json:"browser,omitempty"`
+
+	// CurrentProfile is used when no profile is given on the command line
+	CurrentProfile string             `json:"current_profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles,omitempty"`
+
+	// path is the file the config was read from and is written back to
+	path string
+	// profileName is the name of the profile in use
+	profileName string
+}
+
+// Profile holds the settings that differ between databases, so that one
+// config file can switch between e.g. a local and a shared database
+type Profile struct {
 	CurrentUserName string `json:"current_user_name"`
-	DatabaseURL     string `json:"database_url"`
+	// SessionToken proves that the current user logged in with their password
+	SessionToken string      `json:"session_token,omitempty"`
+	DatabaseURL  string      `json:"database_url"`
+	Fetch        FetchConfig `json:"fetch"`
 }
 
-// Read reads the JSON file found at ~/.gatorconfig.json and returns a Config struct
-func Read() (Config, error) {
-	var cfg Config
-	path, err := getConfigFilePath()
+// RetentionConfig holds the global post retention policy.
+// A zero value disables the corresponding limit; feeds can override both limits.
+type RetentionConfig struct {
+	MaxAgeDays      int  `json:"max_age_days"`
+	MaxPostsPerFeed int  `json:"max_posts_per_feed"`
+	PruneOnAgg      bool `json:"prune_on_agg"`
+}
+
+// Read reads the config file at path, selects a profile and applies the
+// overrides from the GATOR_DB_URL and GATOR_USER environment variables. The
+// profile is the one named, $GATOR_PROFILE or the file's current profile, in
+// that order.
+func Read(path, profile string) (Config, error) {
+	cfg, err := readFile(path)
 	if err != nil {
 		return cfg, err
 	}
 
-	file, err := os.Open(path)
-	if err != nil {
+	if profile == "" {
+		profile = os.Getenv("GATOR_PROFILE")
+	}
+	if profile == "" {
+		profile = cfg.CurrentProfile
+	}
+	if err := cfg.useProfile(profile); err != nil {
 		return cfg, err
 	}
-	defer file.Close()
 
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&cfg); err != nil {
-		return cfg, err
+	if url := os.Getenv("GATOR_DB_URL"); url != "" {
+		cfg.DatabaseURL = url
+	}
+	if user := os.Getenv("GATOR_USER"); user != "" {
+		cfg.CurrentUserName = user
+	}
+	if cfg.DatabaseURL == "" && cfg.profileName != DefaultProfile {
+		return cfg, fmt.Errorf("no database URL for profile %q in %s: set its database_url or the GATOR_DB_URL environment variable", cfg.profileName, path)
+	}
+	if cfg.DatabaseURL == "" {
+		return cfg, fmt.Errorf("no database URL in %s: set database_url or the GATOR_DB_URL environment variable", path)
+	}
+	return cfg, nil
+}
+
+// useProfile replaces the top-level settings with those of the named profile
+func (cfg *Config) useProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		cfg.profileName = DefaultProfile
+		return nil
+	}
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile named %q in %s (run \"gator profile list\" to see them)", name, cfg.path)
+	}
+	cfg.Profile = profile
+	cfg.profileName = name
+	return nil
+}
+
+// readFile decodes the config file without applying any overrides
+func readFile(path string) (Config, error) {
+	cfg := Config{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, fmt.Errorf("config file %s does not exist: run \"gator init\" to create it", path)
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("error reading config file: %v", err)
 	}
 
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	err = json.Unmarshal(data, &cfg)
+	switch {
+	case errors.As(err, &syntaxErr):
+		line := 1 + bytes.Count(data[:syntaxErr.Offset], []byte("\n"))
+		return cfg, fmt.Errorf("error parsing config file %s: line %d: %v", path, line, err)
+	case errors.As(err, &typeErr):
+		return cfg, fmt.Errorf("error parsing config file %s: %s must be %s, not %s", path, typeErr.Field, typeName(typeErr.Type), typeErr.Value)
+	case err != nil:
+		return cfg, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
 	return cfg, nil
 }
 
-// SetUser sets the current_user_name field and writes the config struct to the JSON file
-func (cfg *Config) SetUser(userName string) error {
-	cfg.CurrentUserName = userName
-	return write(*cfg)
+// typeName describes a Go type the way JSON users think of it
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "a whole number"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return t.String()
+}
+
+// Create writes a new config file, refusing to replace an existing one
+// unless overwrite is set
+func Create(path string, cfg Config, overwrite bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error creating config directory: %v", err)
+	}
+	return withLock(path, func() error {
+		if _, err := os.Stat(path); err == nil && !overwrite {
+			return fmt.Errorf("config file %s already exists", path)
+		}
+		cfg.path = path
+		return write(cfg)
+	})
 }
 
-// getConfigFilePath returns the full path to the config file
-func getConfigFilePath() (string, error) {
+// Path returns the path of the config file to use. An explicit path, as
+// given with --config, wins over $GATOR_CONFIG. Otherwise the file lives in
+// $XDG_CONFIG_HOME/gator/config.json, or ~/.config when XDG_CONFIG_HOME is
+// not set, unless only the older ~/.gatorconfig.json exists.
+func Path(explicit string) (string, error) {
+	if explicit != "" {
+		return explicit, nil
+	}
+	if path := os.Getenv("GATOR_CONFIG"); path != "" {
+		return path, nil
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(homeDir, configFileName), nil
-}
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	path := filepath.Join(configDir, "gator", configFileName)
 
-// write writes the config struct to the JSON file
-func write(cfg Config) error {
-	path, err := getConfigFilePath()
-	if err != nil {
-		return err
+	legacyPath := filepath.Join(homeDir, legacyConfigFileName)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Stat(legacyPath); err == nil {
+			return legacyPath, nil
+		}
 	}
+	return path, nil
+}
+
+// File returns the path the config was read from
+func (cfg *Config) File() string {
+	return cfg.path
+}
+
+// ProfileName returns the name of the profile in use
+func (cfg *Config) ProfileName() string {
+	return cfg.profileName
+}
 
-	file, err := os.Create(path)
+// SetSession sets the current user and session token of the profile in use
+// and writes them to the config file. The file is read again first so that
+// environment overrides are not saved.
+func (cfg *Config) SetSession(userName, token string) error {
+	err := update(cfg.path, func(saved *Config) error {
+		return saved.editProfile(cfg.profileName, func(p *Profile) {
+			p.CurrentUserName = userName
+			p.SessionToken = token
+		})
+	})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	cfg.CurrentUserName = userName
+	cfg.SessionToken = token
+	return nil
+}
 
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(cfg); err != nil {
-		return err
-	}
+// FetchConfig controls the HTTP client used to download feeds.
+// Durations use Go syntax such as "30s"; empty values fall back to built-in defaults.
+type FetchConfig struct {
+	Timeout        string `json:"timeout,omitempty"`
+	ConnectTimeout string `json:"connect_timeout,omitempty"`
+	ReadTimeout    string `json:"read_timeout,omitempty"`
+	MaxBodyBytes   int64  `json:"max_body_bytes,omitempty"`
+	UserAgent      string `json:"user_agent,omitempty"`
+	ContactURL     string `json:"contact_url,omitempty"`
+	Proxy          string `json:"proxy,omitempty"`
 
-	return nil
+	// PerHostInterval is the minimum delay between requests to the same host
+	PerHostInterval    string `json:"per_host_interval,omitempty"`
+	PerHostConcurrency int    `json:"per_host_concurrency,omitempty"`
+	RespectRobots      bool   `json:"respect_robots,omitempty"`
 }
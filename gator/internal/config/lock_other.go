@@ -0,0 +1,15 @@
+//go:build !(unix && !aix) && !windows
+
+package config
+
+import "os"
+
+// lockFile does nothing on systems without flock. Writes are still atomic,
+// but concurrent updates may overwrite each other.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,80 @@
+package config
+
+import "fmt"
+
+// ListProfiles returns the profiles in the config file at path by name,
+// including the default profile
+func ListProfiles(path string) (map[string]Profile, error) {
+	cfg, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	profiles := map[string]Profile{DefaultProfile: cfg.Profile}
+	for name, profile := range cfg.Profiles {
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// AddProfile adds a named profile to the config file at path
+func AddProfile(path, name string, profile Profile) error {
+	return update(path, func(cfg *Config) error {
+		if _, exists := cfg.Profiles[name]; exists || name == DefaultProfile {
+			return fmt.Errorf("profile %q already exists", name)
+		}
+		if cfg.Profiles == nil {
+			cfg.Profiles = make(map[string]Profile)
+		}
+		cfg.Profiles[name] = profile
+		return nil
+	})
+}
+
+// RemoveProfile removes a named profile from the config file at path. The
+// default profile and the current profile cannot be removed.
+func RemoveProfile(path, name string) error {
+	return update(path, func(cfg *Config) error {
+		if name == DefaultProfile {
+			return fmt.Errorf("the default profile cannot be removed")
+		}
+		if _, exists := cfg.Profiles[name]; !exists {
+			return fmt.Errorf("no profile named %q", name)
+		}
+		if cfg.CurrentProfile == name {
+			return fmt.Errorf("profile %q is the current profile: switch to another one first", name)
+		}
+		delete(cfg.Profiles, name)
+		return nil
+	})
+}
+
+// UseProfile makes the named profile the current profile of the config file
+// at path
+func UseProfile(path, name string) error {
+	return update(path, func(cfg *Config) error {
+		if name == DefaultProfile {
+			cfg.CurrentProfile = ""
+			return nil
+		}
+		if _, exists := cfg.Profiles[name]; !exists {
+			return fmt.Errorf("no profile named %q", name)
+		}
+		cfg.CurrentProfile = name
+		return nil
+	})
+}
+
+// editProfile changes the named profile in place
+func (cfg *Config) editProfile(name string, fn func(*Profile)) error {
+	if name == "" || name == DefaultProfile {
+		fn(&cfg.Profile)
+		return nil
+	}
+	profile, exists := cfg.Profiles[name]
+	if !exists {
+		return fmt.Errorf("no profile named %q in %s", name, cfg.path)
+	}
+	fn(&profile)
+	cfg.Profiles[name] = profile
+	return nil
+}
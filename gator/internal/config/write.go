@@ -0,0 +1,165 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// update changes the config file at path while holding its lock, so that
+// concurrent updates from other gator processes are not lost
+func update(path string, fn func(*Config) error) error {
+	return withLock(path, func() error {
+		cfg, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(&cfg); err != nil {
+			return err
+		}
+		return write(cfg)
+	})
+}
+
+// withLock runs fn while holding an exclusive lock on a file next to the
+// config file. The config file itself is replaced on every write, so it
+// cannot carry the lock.
+func withLock(path string, fn func() error) error {
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening config lock file: %v", err)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("error locking config file: %v", err)
+	}
+	defer unlockFile(lock)
+	return fn()
+}
+
+// write replaces the config file with cfg. The new contents go to a temporary
+// file that is renamed over the old one, so a crash never leaves a partial
+// file behind. Keys gator does not know about are copied from the old file.
+// Callers must hold the lock.
+func write(cfg Config) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	old, err := os.ReadFile(cfg.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	if len(old) > 0 {
+		data = mergeUnknown(old, data, reflect.TypeOf(cfg))
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cfg.path), "."+filepath.Base(cfg.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// The file holds the database password, so only its owner may read it
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), cfg.path); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	return nil
+}
+
+// mergeUnknown adds the keys of the old JSON object that the type t has no
+// field for to the new object, looking into nested objects as well. The old
+// document is dropped if it is not an object.
+func mergeUnknown(old, data []byte, t reflect.Type) []byte {
+	var oldFields, fields map[string]json.RawMessage
+	if json.Unmarshal(old, &oldFields) != nil || json.Unmarshal(data, &fields) != nil {
+		return data
+	}
+
+	known := jsonFields(t)
+	for key, value := range oldFields {
+		field, isKnown := known[key]
+		newValue, present := fields[key]
+		switch {
+		case !isKnown:
+			fields[key] = value
+		case present && field.Kind() == reflect.Struct:
+			fields[key] = mergeUnknown(value, newValue, field)
+		case present && field.Kind() == reflect.Map && field.Elem().Kind() == reflect.Struct:
+			fields[key] = mergeUnknownEach(value, newValue, field.Elem())
+		}
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return merged
+}
+
+// mergeUnknownEach merges the values of two JSON objects whose values are
+// all of type t
+func mergeUnknownEach(old, data []byte, t reflect.Type) []byte {
+	var oldValues, values map[string]json.RawMessage
+	if json.Unmarshal(old, &oldValues) != nil || json.Unmarshal(data, &values) != nil {
+		return data
+	}
+	for key, value := range values {
+		if oldValue, ok := oldValues[key]; ok {
+			values[key] = mergeUnknown(oldValue, value, t)
+		}
+	}
+	merged, err := json.Marshal(values)
+	if err != nil {
+		return data
+	}
+	return merged
+}
+
+// jsonFields maps the JSON keys of a struct type to the types of their fields
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		// The fields of embedded structs are stored in the outer object
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for embedded, fieldType := range jsonFields(field.Type) {
+				fields[embedded] = fieldType
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
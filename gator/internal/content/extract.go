@@ -0,0 +1,230 @@
+package content
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoArticle is returned when a page has no recognizable main content
+var ErrNoArticle = errors.New("no article content found")
+
+// minArticleLength is the shortest amount of text accepted as an article
+const minArticleLength = 200
+
+var (
+	positiveHint = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text`)
+	negativeHint = regexp.MustCompile(`(?i)ad-|ads|banner|combx|comment|related|footer|footnote|masthead|menu|meta|nav|outbrain|promo|share|shoutbox|sidebar|social|sponsor|subscribe|widget`)
+)
+
+// boilerplateTags never contain the main content of a page
+var boilerplateTags = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Header: true,
+	atom.Footer: true,
+	atom.Aside:  true,
+	atom.Button: true,
+	atom.Select: true,
+	atom.Input:  true,
+}
+
+// ExtractArticle finds the main readable content of an HTML page using
+// readability-style heuristics and returns it as sanitized HTML. Relative links
+// and image sources are resolved against base.
+func ExtractArticle(r io.Reader, base *url.URL) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	removeBoilerplate(doc)
+
+	// Score every paragraph's parent and grandparent, as readability does
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td) {
+			text := textContent(n)
+			if len(text) >= 25 {
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+				for i, ancestor := range []*html.Node{n.Parent, grandparent(n)} {
+					if ancestor == nil || ancestor.Type != html.ElementNode {
+						continue
+					}
+					if _, seen := scores[ancestor]; !seen {
+						scores[ancestor] = classWeight(ancestor)
+						candidates = append(candidates, ancestor)
+					}
+					if i == 0 {
+						scores[ancestor] += score
+					} else {
+						scores[ancestor] += score / 2
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		score := scores[candidate] * (1 - linkDensity(candidate))
+		if best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+
+	// An <article> element is a strong signal when the scoring found nothing better
+	if article := findFirst(doc, atom.Article); article != nil {
+		if best == nil || len(textContent(article)) > 2*len(textContent(best)) {
+			best = article
+		}
+	}
+
+	if best == nil || len(textContent(best)) < minArticleLength {
+		return "", ErrNoArticle
+	}
+
+	resolveURLs(best, base)
+
+	var sb strings.Builder
+	for c := best.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(&sb, c); err != nil {
+			return "", err
+		}
+	}
+	return Sanitize(sb.String()), nil
+}
+
+// removeBoilerplate strips elements that never hold article content, including
+// anything whose class or id looks like navigation, ads or comments
+func removeBoilerplate(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if c.Type == html.CommentNode {
+			n.RemoveChild(c)
+		} else if c.Type == html.ElementNode {
+			hints := attr(c, "class") + " " + attr(c, "id")
+			if droppedTags[c.DataAtom] || boilerplateTags[c.DataAtom] ||
+				(c.DataAtom != atom.Body && c.DataAtom != atom.Html && negativeHint.MatchString(hints) && !positiveHint.MatchString(hints)) {
+				n.RemoveChild(c)
+			} else {
+				removeBoilerplate(c)
+			}
+		}
+		c = next
+	}
+}
+
+// classWeight rewards or penalizes an element based on its class and id
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, hint := range []string{attr(n, "class"), attr(n, "id")} {
+		if hint == "" {
+			continue
+		}
+		if negativeHint.MatchString(hint) {
+			weight -= 25
+		}
+		if positiveHint.MatchString(hint) {
+			weight += 25
+		}
+	}
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		weight += 10
+	case atom.Div:
+		weight += 5
+	case atom.Blockquote, atom.Pre, atom.Td:
+		weight += 3
+	case atom.Form, atom.Ol, atom.Ul, atom.Li, atom.Dl:
+		weight -= 3
+	}
+	return weight
+}
+
+// linkDensity is the fraction of an element's text that sits inside links
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	linked := 0
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			linked += len(textContent(n))
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(linked) / float64(total)
+}
+
+// textContent returns the whitespace-collapsed text inside a node
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return collapseSpace(sb.String())
+}
+
+// resolveURLs rewrites relative href and src attributes to absolute URLs
+func resolveURLs(n *html.Node, base *url.URL) {
+	if base == nil {
+		return
+	}
+	if n.Type == html.ElementNode {
+		for i, a := range n.Attr {
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			if ref, err := url.Parse(strings.TrimSpace(a.Val)); err == nil {
+				n.Attr[i].Val = base.ResolveReference(ref).String()
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		resolveURLs(c, base)
+	}
+}
+
+func grandparent(n *html.Node) *html.Node {
+	if n.Parent == nil {
+		return nil
+	}
+	return n.Parent.Parent
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
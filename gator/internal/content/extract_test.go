@@ -0,0 +1,50 @@
+package content
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const articleText = "This is the story, told at length, with enough words and commas, that the scorer takes it for the main content of the page."
+
+func TestExtractArticle(t *testing.T) {
+	base, err := url.Parse("http://example.com/posts/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := `<html><head><title>Page</title><script>track()</script></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter, it is great, really, you will love it.</p></div>
+<div class="post-content">
+<p>` + articleText + `</p>
+<p>` + articleText + ` <a href="../2">Next</a> <img src="/i.png" alt="pic"></p>
+<script>more()</script>
+</div>
+<div class="comments"><p>First comment, which is long enough to be scored as a paragraph.</p></div>
+<footer><p>Copyright notice, with a long enough sentence to be a paragraph.</p></footer>
+</body></html>`
+
+	got, err := ExtractArticle(strings.NewReader(page), base)
+	if err != nil {
+		t.Fatalf("ExtractArticle: %v", err)
+	}
+	for _, want := range []string{articleText, `<a href="http://example.com/2">Next</a>`, `<img src="http://example.com/i.png" alt="pic">`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("article does not contain %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"Home", "Subscribe", "First comment", "Copyright", "more()", "class="} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("article contains %q:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestExtractArticleTooShort(t *testing.T) {
+	page := `<html><body><div><p>Just one short paragraph of text, nothing more.</p></div></body></html>`
+	if _, err := ExtractArticle(strings.NewReader(page), nil); !errors.Is(err, ErrNoArticle) {
+		t.Errorf("ExtractArticle returned %v, want ErrNoArticle", err)
+	}
+}
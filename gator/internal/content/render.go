@@ -0,0 +1,237 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// RenderText converts an HTML fragment into readable plain text for the terminal.
+// Paragraphs are separated by blank lines, lists are bulleted or numbered, links
+// are replaced by numbered footnotes listed at the end, and text is wrapped to
+// the given width. A width of zero or less disables wrapping.
+func RenderText(fragment string, width int) string {
+	nodes, err := parseFragment(fragment)
+	if err != nil {
+		return wrap(collapseSpace(fragment), width, "", "")
+	}
+
+	r := &renderer{width: width}
+	for _, n := range nodes {
+		r.render(n)
+	}
+	r.flush(false)
+	r.blank()
+
+	for i, link := range r.links {
+		r.lines = append(r.lines, fmt.Sprintf("[%d] %s", i+1, link))
+	}
+	return strings.TrimRight(strings.Join(r.lines, "\n"), "\n ")
+}
+
+// list tracks an open <ul> or <ol> element
+type list struct {
+	ordered bool
+	next    int
+}
+
+type renderer struct {
+	width  int
+	lines  []string
+	links  []string
+	inline strings.Builder
+
+	lists []*list
+	// bullet is the marker for the first line of the current list item
+	bullet string
+	quote  int
+}
+
+// prefix returns the indentation for continuation lines at the current nesting
+func (r *renderer) prefix() string {
+	p := strings.Repeat("> ", r.quote)
+	if len(r.lists) > 0 {
+		p += strings.Repeat("   ", len(r.lists))
+	}
+	return p
+}
+
+// flush wraps and emits the pending inline text. Unless tight is set, a blank
+// line is added afterwards to end the paragraph.
+func (r *renderer) flush(tight bool) {
+	text := collapseSpace(r.inline.String())
+	r.inline.Reset()
+	if text == "" {
+		if r.bullet == "" {
+			return
+		}
+		text = " "
+	}
+
+	rest := r.prefix()
+	first := rest
+	if r.bullet != "" {
+		first = rest[:len(rest)-3] + r.bullet
+		r.bullet = ""
+	}
+	r.lines = append(r.lines, strings.Split(wrap(text, r.width, first, rest), "\n")...)
+	if !tight {
+		r.blank()
+	}
+}
+
+// blank ends the current paragraph with an empty line
+func (r *renderer) blank() {
+	if len(r.lines) > 0 && r.lines[len(r.lines)-1] != "" {
+		r.lines = append(r.lines, "")
+	}
+}
+
+func (r *renderer) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// Whitespace is collapsed when the text is flushed, except inside <pre>
+		r.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		r.children(n)
+		return
+	default:
+		return
+	}
+
+	if droppedTags[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		r.flush(true)
+	case atom.Hr:
+		r.flush(false)
+		r.lines = append(r.lines, r.prefix()+strings.Repeat("-", min(max(r.width, 10), 40)))
+		r.blank()
+	case atom.Img:
+		alt := strings.TrimSpace(attr(n, "alt"))
+		if alt == "" {
+			alt = "image"
+		}
+		r.inline.WriteString(" [" + alt + "] ")
+	case atom.A:
+		r.children(n)
+		href, ok := safeURL(attr(n, "href"))
+		if ok && href != "" && !strings.HasPrefix(href, "#") {
+			r.links = append(r.links, href)
+			fmt.Fprintf(&r.inline, " [%d]", len(r.links))
+		}
+	case atom.Ul, atom.Ol:
+		r.flush(len(r.lists) > 0)
+		r.lists = append(r.lists, &list{ordered: n.DataAtom == atom.Ol, next: 1})
+		r.children(n)
+		r.flush(true)
+		r.lists = r.lists[:len(r.lists)-1]
+		if len(r.lists) == 0 {
+			r.blank()
+		}
+	case atom.Li:
+		r.flush(true)
+		if len(r.lists) > 0 {
+			l := r.lists[len(r.lists)-1]
+			if l.ordered {
+				r.bullet = fmt.Sprintf("%-3s", fmt.Sprintf("%d.", l.next))
+				l.next++
+			} else {
+				r.bullet = " * "
+			}
+		}
+		r.children(n)
+		r.flush(true)
+	case atom.Blockquote:
+		r.flush(false)
+		r.quote++
+		r.children(n)
+		r.flush(false)
+		r.quote--
+	case atom.Pre:
+		r.flush(false)
+		r.children(n)
+		text := strings.Trim(r.inline.String(), "\n")
+		r.inline.Reset()
+		for _, line := range strings.Split(text, "\n") {
+			r.lines = append(r.lines, r.prefix()+"    "+strings.TrimRight(line, " \t\r"))
+		}
+		r.blank()
+	case atom.Tr:
+		r.flush(true)
+		r.children(n)
+		r.flush(true)
+	case atom.Td, atom.Th:
+		r.children(n)
+		r.inline.WriteString("  ")
+	case atom.P, atom.Div, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Figure, atom.Figcaption, atom.Table, atom.Dl, atom.Dt, atom.Dd,
+		atom.Section, atom.Article, atom.Header, atom.Footer, atom.Aside, atom.Main:
+		r.flush(len(r.lists) > 0)
+		r.children(n)
+		r.flush(len(r.lists) > 0)
+	default:
+		r.children(n)
+	}
+}
+
+func (r *renderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.render(c)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collapseSpace replaces runs of whitespace with single spaces
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// wrap breaks text into lines no wider than width, starting the first line with
+// first and every following line with rest
+func wrap(text string, width int, first, rest string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return strings.TrimRight(first, " ")
+	}
+
+	var sb strings.Builder
+	line := first
+	lineLen := utf8.RuneCountInString(first)
+	empty := true
+	for _, word := range words {
+		wordLen := utf8.RuneCountInString(word)
+		if !empty && width > 0 && lineLen+1+wordLen > width {
+			sb.WriteString(line)
+			sb.WriteString("\n")
+			line = rest
+			lineLen = utf8.RuneCountInString(rest)
+			empty = true
+		}
+		if !empty {
+			line += " "
+			lineLen++
+		}
+		line += word
+		lineLen += wordLen
+		empty = false
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package content
+
+import "testing"
+
+func TestRenderText(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width int
+		want  string
+	}{
+		{
+			name: "paragraphs",
+			in:   "<p>One\n  two</p><p>Three</p>",
+			want: "One two\n\nThree",
+		},
+		{
+			name: "links become footnotes",
+			in:   `<p>See <a href="http://a">this</a> and <a href="http://b">that</a>, not <a href="#top">here</a> or <a href="javascript:x()">there</a>.</p>`,
+			want: "See this [1] and that [2], not here or there.\n\n[1] http://a\n[2] http://b",
+		},
+		{
+			name: "lists",
+			in:   "<ul><li>a</li><li>b</li></ul><ol><li>c</li><li>d</li></ol>",
+			want: " * a\n * b\n\n1. c\n2. d",
+		},
+		{
+			name:  "wrapping",
+			in:    "<p>the quick brown fox jumps over the lazy dog</p>",
+			width: 15,
+			want:  "the quick brown\nfox jumps over\nthe lazy dog",
+		},
+		{
+			name:  "wrapped list items are indented",
+			in:    "<ul><li>the quick brown fox</li></ul>",
+			width: 12,
+			want:  " * the quick\n   brown fox",
+		},
+		{
+			name:  "quotes",
+			in:    "<blockquote>quoted text here</blockquote>",
+			width: 12,
+			want:  "> quoted\n> text here",
+		},
+		{
+			name: "preformatted text keeps its lines",
+			in:   "<pre>a  b\n  c</pre>",
+			want: "    a  b\n      c",
+		},
+		{
+			name: "scripts and images",
+			in:   `<p>a<script>x()</script><img alt="cat"> b</p>`,
+			want: "a [cat] b",
+		},
+		{
+			name: "no wrapping without a width",
+			in:   "<p>the quick brown fox jumps over the lazy dog</p>",
+			want: "the quick brown fox jumps over the lazy dog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderText(tt.in, tt.width); got != tt.want {
+				t.Errorf("RenderText(%q, %d) =\n%s\nwant\n%s", tt.in, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package content
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags lists the elements kept by Sanitize and the attributes allowed on each
+var allowedTags = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.B:          nil,
+	atom.Blockquote: nil,
+	atom.Br:         nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title"},
+	atom.Li:         nil,
+	atom.Ol:         nil,
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.S:          nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         nil,
+	atom.Th:         nil,
+	atom.Thead:      nil,
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedTags are removed together with everything inside them
+var droppedTags = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Form:     true,
+	atom.Head:     true,
+	atom.Title:    true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+// Sanitize reduces an HTML fragment to a safe allowlist of formatting elements.
+// Scripts, styles and embedded content are removed, attributes other than links
+// and image sources are dropped, and only http, https and mailto URLs are kept.
+func Sanitize(fragment string) string {
+	nodes, err := parseFragment(fragment)
+	if err != nil {
+		return html.EscapeString(fragment)
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		sanitizeNode(&sb, n)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// parseFragment parses HTML in the context of a <body> element
+func parseFragment(fragment string) ([]*html.Node, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	return html.ParseFragment(strings.NewReader(fragment), body)
+}
+
+func sanitizeNode(sb *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		sb.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		// Comments and doctypes are dropped; documents are unwrapped
+		if n.Type == html.DocumentNode {
+			sanitizeChildren(sb, n)
+		}
+		return
+	}
+
+	if droppedTags[n.DataAtom] {
+		return
+	}
+	attrs, allowed := allowedTags[n.DataAtom]
+	if !allowed {
+		// Unknown elements are unwrapped so their text survives
+		sanitizeChildren(sb, n)
+		return
+	}
+
+	sb.WriteString("<")
+	sb.WriteString(n.Data)
+	for _, attr := range n.Attr {
+		if attr.Namespace != "" || !contains(attrs, attr.Key) {
+			continue
+		}
+		value := attr.Val
+		if attr.Key == "href" || attr.Key == "src" {
+			var ok bool
+			if value, ok = safeURL(value); !ok {
+				continue
+			}
+		}
+		sb.WriteString(" ")
+		sb.WriteString(attr.Key)
+		sb.WriteString(`="`)
+		sb.WriteString(html.EscapeString(value))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(">")
+
+	if isVoid(n.DataAtom) {
+		return
+	}
+	sanitizeChildren(sb, n)
+	sb.WriteString("</")
+	sb.WriteString(n.Data)
+	sb.WriteString(">")
+}
+
+func sanitizeChildren(sb *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sanitizeNode(sb, c)
+	}
+}
+
+// safeURL returns the URL if it uses a scheme that is safe to follow
+func safeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto", "":
+		return raw, true
+	}
+	return "", false
+}
+
+func isVoid(a atom.Atom) bool {
+	return a == atom.Br || a == atom.Hr || a == atom.Img
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
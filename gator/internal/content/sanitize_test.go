@@ -0,0 +1,31 @@
+package content
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "a < b & c", "a &lt; b &amp; c"},
+		{"allowed tags", "<p>Hi <b>there</b><br></p>", "<p>Hi <b>there</b><br></p>"},
+		{"script dropped with its content", "<p>a<script>alert(1)</script>b</p>", "<p>ab</p>"},
+		{"style and iframe dropped", "<style>p{}</style><iframe src=\"http://x\">x</iframe>ok", "ok"},
+		{"unknown tags unwrapped", "<custom>kept <font>text</font></custom>", "kept text"},
+		{"event handlers dropped", "<p onclick=\"x()\" class=\"c\">a</p>", "<p>a</p>"},
+		{"javascript link dropped", "<a href=\"javascript:alert(1)\" title=\"t\">a</a>", "<a title=\"t\">a</a>"},
+		{"mixed case javascript", "<a href=\" JavaScript:alert(1)\">a</a>", "<a>a</a>"},
+		{"data image dropped", "<img src=\"data:image/png;base64,AA\" alt=\"x\">", "<img alt=\"x\">"},
+		{"safe urls kept", "<a href=\"https://x/?a=1&amp;b=2\">a</a><a href=\"mailto:a@x\">m</a><a href=\"/rel\">r</a>", "<a href=\"https://x/?a=1&amp;b=2\">a</a><a href=\"mailto:a@x\">m</a><a href=\"/rel\">r</a>"},
+		{"comments dropped", "a<!-- secret -->b", "ab"},
+		{"attribute values escaped", "<img alt='\"&lt;x&gt;' src=\"http://x/i.png\">", "<img alt=\"&#34;&lt;x&gt;\" src=\"http://x/i.png\">"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -57,8 +58,93 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (CreateF
 	return i, err
 }
 
+const deferFeed = `-- name: DeferFeed :exec
+UPDATE feeds
+SET next_fetch_at = NOW() + make_interval(secs => $1::float8), updated_at = NOW()
+WHERE id = $2
+`
+
+type DeferFeedParams struct {
+	DelaySeconds float64
+	ID           uuid.UUID
+}
+
+func (q *Queries) DeferFeed(ctx context.Context, arg DeferFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeed, arg.DelaySeconds, arg.ID)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getFeedInfoByUrl = `-- name: GetFeedInfoByUrl :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.retention_max_age_days, feeds.retention_max_posts, feeds.site_title, feeds.site_description, feeds.site_link, feeds.language, feeds.image_url, feeds.generator, feeds.dead_at, feeds.next_fetch_at, feeds.parse_warning, feeds.fetch_full_content, users.name AS user_name
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.url = $1
+OR feeds.id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1)
+`
+
+type GetFeedInfoByUrlRow struct {
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
+	SiteTitle           sql.NullString
+	SiteDescription     sql.NullString
+	SiteLink            sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	DeadAt              sql.NullTime
+	NextFetchAt         sql.NullTime
+	ParseWarning        sql.NullString
+	FetchFullContent    bool
+	UserName            string
+}
+
+func (q *Queries) GetFeedInfoByUrl(ctx context.Context, url string) (GetFeedInfoByUrlRow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedInfoByUrl, url)
+	var i GetFeedInfoByUrlRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+		&i.SiteTitle,
+		&i.SiteDescription,
+		&i.SiteLink,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.DeadAt,
+		&i.NextFetchAt,
+		&i.ParseWarning,
+		&i.FetchFullContent,
+		&i.UserName,
+	)
+	return i, err
+}
+
 const getFeedsWithUserNames = `-- name: GetFeedsWithUserNames :many
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name AS feed_name, feeds.url, users.name AS user_name
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name AS feed_name, feeds.url, feeds.site_title, feeds.site_link, users.name AS user_name
 FROM feeds
 JOIN users ON feeds.user_id = users.id
 `
@@ -69,6 +155,8 @@ type GetFeedsWithUserNamesRow struct {
 	UpdatedAt time.Time
 	FeedName  string
 	Url       string
+	SiteTitle sql.NullString
+	SiteLink  sql.NullString
 	UserName  string
 }
 
@@ -87,6 +175,8 @@ func (q *Queries) GetFeedsWithUserNames(ctx context.Context) ([]GetFeedsWithUser
 			&i.UpdatedAt,
 			&i.FeedName,
 			&i.Url,
+			&i.SiteTitle,
+			&i.SiteLink,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -102,26 +192,67 @@ func (q *Queries) GetFeedsWithUserNames(ctx context.Context) ([]GetFeedsWithUser
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, retention_max_age_days, retention_max_posts, site_title, site_description, site_link, language, image_url, generator, dead_at, next_fetch_at, parse_warning, fetch_full_content
 FROM feeds
+WHERE dead_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
 ORDER BY last_fetched_at NULLS FIRST, updated_at
-LIMIT 1
+LIMIT $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.RetentionMaxAgeDays,
+			&i.RetentionMaxPosts,
+			&i.SiteTitle,
+			&i.SiteDescription,
+			&i.SiteLink,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.DeadAt,
+			&i.NextFetchAt,
+			&i.ParseWarning,
+			&i.FetchFullContent,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedDead = `-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkFeedDead(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedDead, id)
+	return err
 }
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
@@ -134,3 +265,108 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const reviveFeed = `-- name: ReviveFeed :exec
+UPDATE feeds
+SET dead_at = NULL, next_fetch_at = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) ReviveFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, reviveFeed, id)
+	return err
+}
+
+const setFeedFetchFullContent = `-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedFetchFullContentParams struct {
+	ID               uuid.UUID
+	FetchFullContent bool
+}
+
+func (q *Queries) SetFeedFetchFullContent(ctx context.Context, arg SetFeedFetchFullContentParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedFetchFullContent, arg.ID, arg.FetchFullContent)
+	return err
+}
+
+const setFeedParseWarning = `-- name: SetFeedParseWarning :exec
+UPDATE feeds
+SET parse_warning = $2
+WHERE id = $1
+`
+
+type SetFeedParseWarningParams struct {
+	ID           uuid.UUID
+	ParseWarning sql.NullString
+}
+
+func (q *Queries) SetFeedParseWarning(ctx context.Context, arg SetFeedParseWarningParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedParseWarning, arg.ID, arg.ParseWarning)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_days = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID                  uuid.UUID
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention, arg.ID, arg.RetentionMaxAgeDays, arg.RetentionMaxPosts)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET site_title = $2, site_description = $3, site_link = $4, language = $5, image_url = $6, generator = $7, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID              uuid.UUID
+	SiteTitle       sql.NullString
+	SiteDescription sql.NullString
+	SiteLink        sql.NullString
+	Language        sql.NullString
+	ImageUrl        sql.NullString
+	Generator       sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.SiteTitle,
+		arg.SiteDescription,
+		arg.SiteLink,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	return err
+}
+
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url)
+	return err
+}
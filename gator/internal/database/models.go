@@ -12,13 +12,31 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	RetentionMaxAgeDays sql.NullInt32
+	RetentionMaxPosts   sql.NullInt32
+	SiteTitle           sql.NullString
+	SiteDescription     sql.NullString
+	SiteLink            sql.NullString
+	Language            sql.NullString
+	ImageUrl            sql.NullString
+	Generator           sql.NullString
+	DeadAt              sql.NullTime
+	NextFetchAt         sql.NullTime
+	ParseWarning        sql.NullString
+	FetchFullContent    bool
+}
+
+type FeedAlias struct {
+	Url       string
+	FeedID    uuid.UUID
+	CreatedAt time.Time
 }
 
 type FeedFollow struct {
@@ -38,11 +56,54 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Author      sql.NullString
+	Categories  []string
+}
+
+type PostEnclosure struct {
+	ID       uuid.UUID
+	PostID   uuid.UUID
+	Url      string
+	MimeType sql.NullString
+	Length   sql.NullInt64
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type SavedPost struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type Session struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
 }
 
 type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	PasswordHash sql.NullString
+}
+
+type UserFilter struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	Action    string
+	Field     string
+	Pattern   string
+	IsRegex   bool
+	FeedID    uuid.NullUUID
 }
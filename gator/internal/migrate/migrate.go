@@ -0,0 +1,315 @@
+// Package migrate applies the goose-style SQL migrations embedded in gator.
+// It records applied versions in goose's goose_db_version table, so databases
+// migrated with the goose command-line tool and with gator are interchangeable.
+// The version table is created the way goose creates it for PostgreSQL and for
+// SQLite.
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one schema file, split into its up and down statements
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// NoTransaction is set by a "-- +goose NO TRANSACTION" annotation
+	NoTransaction bool
+}
+
+// Status reports whether a migration has been applied to a database
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Dialect holds the statements that manage the version table, which differ
+// between databases
+type Dialect struct {
+	createTable   string
+	tableExists   string
+	insertVersion string
+	deleteVersion string
+}
+
+var (
+	Postgres = &Dialect{
+		createTable: `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP NULL DEFAULT NOW()
+)`,
+		tableExists:   `SELECT to_regclass('goose_db_version') IS NOT NULL`,
+		insertVersion: `INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)`,
+		deleteVersion: `DELETE FROM goose_db_version WHERE version_id = $1`,
+	}
+	SQLite = &Dialect{
+		createTable: `CREATE TABLE IF NOT EXISTS goose_db_version (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`,
+		tableExists:   `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'goose_db_version')`,
+		insertVersion: `INSERT INTO goose_db_version (version_id, is_applied) VALUES (?, TRUE)`,
+		deleteVersion: `DELETE FROM goose_db_version WHERE version_id = ?`,
+	}
+)
+
+// Load reads every .sql file in the root of fsys. File names start with the
+// version number, as in 001_users.sql.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int64]string)
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: file name must start with a version number", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, name)
+		}
+		seen[version] = name
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		migration, err := parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %v", name, err)
+		}
+		migration.Version = version
+		migration.Name = strings.TrimSuffix(path.Base(name), ".sql")
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// parse splits a migration file at its "-- +goose Up" and "-- +goose Down"
+// annotations. Statement blocks need no special handling because each
+// section is sent to the server as a whole.
+func parse(text string) (Migration, error) {
+	var m Migration
+	var up, down strings.Builder
+	var section *strings.Builder
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose "); ok {
+			switch strings.ToUpper(strings.TrimSpace(annotation)) {
+			case "UP":
+				section = &up
+			case "DOWN":
+				section = &down
+			case "NO TRANSACTION":
+				m.NoTransaction = true
+			case "STATEMENTBEGIN", "STATEMENTEND":
+			default:
+				return m, fmt.Errorf("unknown annotation %q", line)
+			}
+			continue
+		}
+		if section != nil {
+			section.WriteString(line)
+			section.WriteString("\n")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return m, err
+	}
+	if strings.TrimSpace(up.String()) == "" {
+		return m, fmt.Errorf("missing -- +goose Up section")
+	}
+
+	m.Up = strings.TrimSpace(up.String())
+	m.Down = strings.TrimSpace(down.String())
+	return m, nil
+}
+
+// ensureVersionTable creates goose's version table the way goose does,
+// including the initial version 0 row
+func ensureVersionTable(ctx context.Context, db *sql.DB, d *Dialect) error {
+	_, err := db.ExecContext(ctx, d.createTable)
+	if err != nil {
+		return fmt.Errorf("error creating goose_db_version table: %v", err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO goose_db_version (version_id, is_applied)
+SELECT 0, TRUE
+WHERE NOT EXISTS (SELECT 1 FROM goose_db_version)`)
+	if err != nil {
+		return fmt.Errorf("error initializing goose_db_version table: %v", err)
+	}
+	return nil
+}
+
+// applied returns the applied versions and when they were applied. As in
+// goose, the newest row for a version decides whether it is applied.
+func applied(ctx context.Context, db *sql.DB, d *Dialect) (map[int64]time.Time, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, d.tableExists).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("error checking for goose_db_version table: %v", err)
+	}
+	versions := make(map[int64]time.Time)
+	if !exists {
+		return versions, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT version_id, is_applied, tstamp FROM goose_db_version ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading goose_db_version table: %v", err)
+	}
+	defer rows.Close()
+
+	decided := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+		if version == 0 || decided[version] {
+			continue
+		}
+		decided[version] = true
+		if isApplied {
+			versions[version] = tstamp.Time
+		}
+	}
+	return versions, rows.Err()
+}
+
+// Statuses reports which migrations have been applied
+func Statuses(ctx context.Context, db *sql.DB, d *Dialect, migrations []Migration) ([]Status, error) {
+	versions, err := applied(ctx, db, d)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := versions[m.Version]
+		statuses = append(statuses, Status{Migration: m, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+// Version returns the highest applied version, or 0 for an empty database
+func Version(ctx context.Context, db *sql.DB, d *Dialect) (int64, error) {
+	versions, err := applied(ctx, db, d)
+	if err != nil {
+		return 0, err
+	}
+	current := int64(0)
+	for version := range versions {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+func Up(ctx context.Context, db *sql.DB, d *Dialect, migrations []Migration) ([]Migration, error) {
+	if err := ensureVersionTable(ctx, db, d); err != nil {
+		return nil, err
+	}
+	versions, err := applied(ctx, db, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range migrations {
+		if _, ok := versions[m.Version]; ok {
+			continue
+		}
+		err := run(ctx, db, m, m.Up, d.insertVersion)
+		if err != nil {
+			return done, fmt.Errorf("error applying migration %s: %v", m.Name, err)
+		}
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// Down rolls back the most recently applied migration. It returns nil when
+// nothing is applied.
+func Down(ctx context.Context, db *sql.DB, d *Dialect, migrations []Migration) (*Migration, error) {
+	current, err := Version(ctx, db, d)
+	if err != nil || current == 0 {
+		return nil, err
+	}
+	for _, m := range migrations {
+		if m.Version != current {
+			continue
+		}
+		err := run(ctx, db, m, m.Down, d.deleteVersion)
+		if err != nil {
+			return nil, fmt.Errorf("error rolling back migration %s: %v", m.Name, err)
+		}
+		return &m, nil
+	}
+	return nil, fmt.Errorf("applied version %d has no migration file", current)
+}
+
+// run executes a migration section and records the new version, inside one
+// transaction unless the migration opts out
+func run(ctx context.Context, db *sql.DB, m Migration, statements, record string) error {
+	if m.NoTransaction {
+		if statements != "" {
+			if _, err := db.ExecContext(ctx, statements); err != nil {
+				return err
+			}
+		}
+		_, err := db.ExecContext(ctx, record, m.Version)
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if statements != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, m.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Latest returns the highest version among the migrations
+func Latest(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
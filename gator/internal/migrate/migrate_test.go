@@ -0,0 +1,147 @@
+package migrate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    Migration
+		wantErr string
+	}{
+		{
+			name: "up and down",
+			text: "-- +goose Up\nCREATE TABLE a (id INT);\n\n-- +goose Down\nDROP TABLE a;\n",
+			want: Migration{Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+		},
+		{
+			name: "text before the first section is ignored",
+			text: "-- a comment\n-- +goose Up\nCREATE TABLE a (id INT);\n",
+			want: Migration{Up: "CREATE TABLE a (id INT);"},
+		},
+		{
+			name: "statement blocks stay in their section",
+			text: "-- +goose Up\n-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS INT AS $$ SELECT 1; $$ LANGUAGE SQL;\n-- +goose StatementEnd\n-- +goose Down\nDROP FUNCTION f;\n",
+			want: Migration{Up: "CREATE FUNCTION f() RETURNS INT AS $$ SELECT 1; $$ LANGUAGE SQL;", Down: "DROP FUNCTION f;"},
+		},
+		{
+			name: "no transaction",
+			text: "-- +goose NO TRANSACTION\n-- +goose Up\nCREATE INDEX CONCURRENTLY i ON a (id);\n",
+			want: Migration{Up: "CREATE INDEX CONCURRENTLY i ON a (id);", NoTransaction: true},
+		},
+		{
+			name: "annotations ignore case and indentation",
+			text: "  -- +goose up\nSELECT 1;\n-- +goose down\nSELECT 2;\n",
+			want: Migration{Up: "SELECT 1;", Down: "SELECT 2;"},
+		},
+		{
+			name:    "unknown annotation",
+			text:    "-- +goose Up\n-- +goose Sideways\nSELECT 1;\n",
+			wantErr: "unknown annotation",
+		},
+		{
+			name:    "missing up section",
+			text:    "-- +goose Down\nDROP TABLE a;\n",
+			wantErr: "missing -- +goose Up section",
+		},
+		{
+			name:    "empty up section",
+			text:    "-- +goose Up\n\n-- +goose Down\nDROP TABLE a;\n",
+			wantErr: "missing -- +goose Up section",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parse returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(up string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte("-- +goose Up\n" + up + "\n")}
+	}
+
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		wantNames    []string
+		wantVersions []int64
+		wantErr      string
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"010_posts.sql": file("SELECT 10;"),
+				"002_feeds.sql": file("SELECT 2;"),
+				"001_users.sql": file("SELECT 1;"),
+				"README.md":     {Data: []byte("not a migration")},
+			},
+			wantNames:    []string{"001_users", "002_feeds", "010_posts"},
+			wantVersions: []int64{1, 2, 10},
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"001_users.sql": file("SELECT 1;"),
+				"1_feeds.sql":   file("SELECT 1;"),
+			},
+			wantErr: "have the same version",
+		},
+		{
+			name:    "bad file name",
+			fsys:    fstest.MapFS{"users.sql": file("SELECT 1;")},
+			wantErr: "file name must start with a version number",
+		},
+		{
+			name:    "version zero",
+			fsys:    fstest.MapFS{"000_users.sql": file("SELECT 1;")},
+			wantErr: "file name must start with a version number",
+		},
+		{
+			name:    "parse error names the file",
+			fsys:    fstest.MapFS{"001_users.sql": {Data: []byte("SELECT 1;\n")}},
+			wantErr: "migration 001_users.sql: missing -- +goose Up section",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load returned %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			var names []string
+			var versions []int64
+			for _, m := range migrations {
+				names = append(names, m.Name)
+				versions = append(versions, m.Version)
+			}
+			if !slices.Equal(names, tt.wantNames) || !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("Load = %v %v, want %v %v", names, versions, tt.wantNames, tt.wantVersions)
+			}
+		})
+	}
+}
@@ -0,0 +1,1111 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+// Memory keeps everything in memory. It enforces the same unique keys and
+// cascading deletes as the database schema, which makes it a stand-in for
+// PostgreSQL in tests.
+type Memory struct {
+	mu sync.Mutex
+	t  tables
+}
+
+// tables holds the rows of every table in insertion order
+type tables struct {
+	users      []database.User
+	feeds      []database.Feed
+	aliases    []database.FeedAlias
+	follows    []database.FeedFollow
+	posts      []database.Post
+	enclosures []database.PostEnclosure
+	reads      []database.PostRead
+	saved      []database.SavedPost
+	filters    []database.UserFilter
+	sessions   []database.Session
+}
+
+var _ Store = (*Memory)(nil)
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (t tables) clone() tables {
+	return tables{
+		users:      slices.Clone(t.users),
+		feeds:      slices.Clone(t.feeds),
+		aliases:    slices.Clone(t.aliases),
+		follows:    slices.Clone(t.follows),
+		posts:      slices.Clone(t.posts),
+		enclosures: slices.Clone(t.enclosures),
+		reads:      slices.Clone(t.reads),
+		saved:      slices.Clone(t.saved),
+		filters:    slices.Clone(t.filters),
+		sessions:   slices.Clone(t.sessions),
+	}
+}
+
+// InTx runs fn against a copy of the tables that replaces them when fn
+// succeeds. Other callers wait until the transaction ends, so no write is lost
+// when the copy is swapped in; fn must only use the store it is given.
+func (m *Memory) InTx(ctx context.Context, fn func(Store) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tx := &Memory{t: m.t.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.t = tx.t
+	return nil
+}
+
+func conflict(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrConflict}, args...)...)
+}
+
+func (t *tables) user(id uuid.UUID) (*database.User, bool) {
+	for i := range t.users {
+		if t.users[i].ID == id {
+			return &t.users[i], true
+		}
+	}
+	return nil, false
+}
+
+func (t *tables) feed(id uuid.UUID) (*database.Feed, bool) {
+	for i := range t.feeds {
+		if t.feeds[i].ID == id {
+			return &t.feeds[i], true
+		}
+	}
+	return nil, false
+}
+
+// feedByURL finds a feed by its URL or by one of its aliases
+func (t *tables) feedByURL(url string) (*database.Feed, bool) {
+	aliasOf := uuid.Nil
+	for _, alias := range t.aliases {
+		if alias.Url == url {
+			aliasOf = alias.FeedID
+		}
+	}
+	for i := range t.feeds {
+		if t.feeds[i].Url == url || t.feeds[i].ID == aliasOf {
+			return &t.feeds[i], true
+		}
+	}
+	return nil, false
+}
+
+func (t *tables) post(id uuid.UUID) (*database.Post, bool) {
+	for i := range t.posts {
+		if t.posts[i].ID == id {
+			return &t.posts[i], true
+		}
+	}
+	return nil, false
+}
+
+func (t *tables) isFollowing(userID, feedID uuid.UUID) bool {
+	for _, follow := range t.follows {
+		if follow.UserID == userID && follow.FeedID == feedID {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tables) isRead(userID, postID uuid.UUID) bool {
+	for _, read := range t.reads {
+		if read.UserID == userID && read.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tables) isSaved(userID, postID uuid.UUID) bool {
+	for _, saved := range t.saved {
+		if saved.UserID == userID && saved.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteUsers removes the matching users and everything that references them
+func (t *tables) deleteUsers(match func(database.User) bool) {
+	var ids []uuid.UUID
+	t.users = slices.DeleteFunc(t.users, func(u database.User) bool {
+		if match(u) {
+			ids = append(ids, u.ID)
+			return true
+		}
+		return false
+	})
+	t.deleteFeeds(func(f database.Feed) bool { return slices.Contains(ids, f.UserID) })
+	t.follows = slices.DeleteFunc(t.follows, func(f database.FeedFollow) bool { return slices.Contains(ids, f.UserID) })
+	t.filters = slices.DeleteFunc(t.filters, func(f database.UserFilter) bool { return slices.Contains(ids, f.UserID) })
+	t.reads = slices.DeleteFunc(t.reads, func(r database.PostRead) bool { return slices.Contains(ids, r.UserID) })
+	t.saved = slices.DeleteFunc(t.saved, func(s database.SavedPost) bool { return slices.Contains(ids, s.UserID) })
+	t.sessions = slices.DeleteFunc(t.sessions, func(s database.Session) bool { return slices.Contains(ids, s.UserID) })
+}
+
+// deleteFeeds removes the matching feeds and everything that references them
+func (t *tables) deleteFeeds(match func(database.Feed) bool) {
+	var ids []uuid.UUID
+	t.feeds = slices.DeleteFunc(t.feeds, func(f database.Feed) bool {
+		if match(f) {
+			ids = append(ids, f.ID)
+			return true
+		}
+		return false
+	})
+	t.aliases = slices.DeleteFunc(t.aliases, func(a database.FeedAlias) bool { return slices.Contains(ids, a.FeedID) })
+	t.follows = slices.DeleteFunc(t.follows, func(f database.FeedFollow) bool { return slices.Contains(ids, f.FeedID) })
+	t.filters = slices.DeleteFunc(t.filters, func(f database.UserFilter) bool {
+		return f.FeedID.Valid && slices.Contains(ids, f.FeedID.UUID)
+	})
+	t.deletePosts(func(p database.Post) bool { return slices.Contains(ids, p.FeedID) })
+}
+
+// deletePosts removes the matching posts and everything that references them
+// and returns how many posts were removed
+func (t *tables) deletePosts(match func(database.Post) bool) int64 {
+	var ids []uuid.UUID
+	t.posts = slices.DeleteFunc(t.posts, func(p database.Post) bool {
+		if match(p) {
+			ids = append(ids, p.ID)
+			return true
+		}
+		return false
+	})
+	t.enclosures = slices.DeleteFunc(t.enclosures, func(e database.PostEnclosure) bool { return slices.Contains(ids, e.PostID) })
+	t.reads = slices.DeleteFunc(t.reads, func(r database.PostRead) bool { return slices.Contains(ids, r.PostID) })
+	t.saved = slices.DeleteFunc(t.saved, func(s database.SavedPost) bool { return slices.Contains(ids, s.PostID) })
+	return int64(len(ids))
+}
+
+// newestFirst orders posts like ORDER BY published_at DESC, id in
+// PostgreSQL, where posts without a date sort first
+func newestFirst(a, b database.Post) int {
+	switch {
+	case a.PublishedAt.Valid != b.PublishedAt.Valid:
+		if !a.PublishedAt.Valid {
+			return -1
+		}
+		return 1
+	case a.PublishedAt.Valid && !a.PublishedAt.Time.Equal(b.PublishedAt.Time):
+		return b.PublishedAt.Time.Compare(a.PublishedAt.Time)
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+// copyPost returns a post that shares no memory with the stored one
+func copyPost(p database.Post) database.Post {
+	p.Categories = slices.Clone(p.Categories)
+	return p
+}
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.t.users {
+		if user.ID == arg.ID || user.Name == arg.Name {
+			return database.User{}, conflict("user %s", arg.Name)
+		}
+	}
+	user := database.User{ID: arg.ID, CreatedAt: arg.CreatedAt, UpdatedAt: arg.UpdatedAt, Name: arg.Name, PasswordHash: arg.PasswordHash}
+	m.t.users = append(m.t.users, user)
+	return user, nil
+}
+
+func (m *Memory) DeleteAllUsers(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.t.deleteUsers(func(database.User) bool { return true })
+	return nil
+}
+
+func (m *Memory) GetUserByName(ctx context.Context, name string) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, user := range m.t.users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetUsers(ctx context.Context) ([]database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.t.users), nil
+}
+
+func (m *Memory) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if user, ok := m.t.user(arg.ID); ok {
+		user.PasswordHash = arg.PasswordHash
+		user.UpdatedAt = arg.UpdatedAt
+	}
+	return nil
+}
+
+func (m *Memory) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.user(arg.UserID); !ok {
+		return fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	for _, session := range m.t.sessions {
+		if session.TokenHash == arg.TokenHash {
+			return conflict("session")
+		}
+	}
+	now := time.Now()
+	m.t.sessions = append(m.t.sessions, database.Session{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(arg.LifetimeSeconds * float64(time.Second))),
+	})
+	return nil
+}
+
+func (m *Memory) DeleteExpiredSessions(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.t.sessions = slices.DeleteFunc(m.t.sessions, func(s database.Session) bool {
+		return !s.ExpiresAt.After(now)
+	})
+	return nil
+}
+
+func (m *Memory) DeleteSession(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.t.sessions = slices.DeleteFunc(m.t.sessions, func(s database.Session) bool {
+		return s.TokenHash == tokenHash
+	})
+	return nil
+}
+
+func (m *Memory) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.t.sessions = slices.DeleteFunc(m.t.sessions, func(s database.Session) bool {
+		return s.UserID == userID
+	})
+	return nil
+}
+
+func (m *Memory) GetSession(ctx context.Context, tokenHash string) (database.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, session := range m.t.sessions {
+		if session.TokenHash == tokenHash && session.ExpiresAt.After(time.Now()) {
+			return session, nil
+		}
+	}
+	return database.Session{}, sql.ErrNoRows
+}
+
+func (m *Memory) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.CreateFeedRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.user(arg.UserID); !ok {
+		return database.CreateFeedRow{}, fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	for _, feed := range m.t.feeds {
+		if feed.ID == arg.ID || feed.Url == arg.Url {
+			return database.CreateFeedRow{}, conflict("feed %s", arg.Url)
+		}
+	}
+	m.t.feeds = append(m.t.feeds, database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	})
+	return database.CreateFeedRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}, nil
+}
+
+func (m *Memory) DeferFeed(ctx context.Context, arg database.DeferFeedParams) error {
+	return m.updateFeed(arg.ID, func(feed *database.Feed) {
+		delay := time.Duration(arg.DelaySeconds * float64(time.Second))
+		feed.NextFetchAt = sql.NullTime{Time: time.Now().Add(delay), Valid: true}
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.t.deleteFeeds(func(f database.Feed) bool { return f.ID == id })
+	return nil
+}
+
+func (m *Memory) GetFeedByUrl(ctx context.Context, url string) (database.GetFeedByUrlRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed, ok := m.t.feedByURL(url)
+	if !ok {
+		return database.GetFeedByUrlRow{}, sql.ErrNoRows
+	}
+	return database.GetFeedByUrlRow{
+		ID:        feed.ID,
+		CreatedAt: feed.CreatedAt,
+		UpdatedAt: feed.UpdatedAt,
+		Name:      feed.Name,
+		Url:       feed.Url,
+		UserID:    feed.UserID,
+	}, nil
+}
+
+func (m *Memory) GetFeedInfoByUrl(ctx context.Context, url string) (database.GetFeedInfoByUrlRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed, ok := m.t.feedByURL(url)
+	if !ok {
+		return database.GetFeedInfoByUrlRow{}, sql.ErrNoRows
+	}
+	user, ok := m.t.user(feed.UserID)
+	if !ok {
+		return database.GetFeedInfoByUrlRow{}, sql.ErrNoRows
+	}
+	return database.GetFeedInfoByUrlRow{
+		ID:                  feed.ID,
+		CreatedAt:           feed.CreatedAt,
+		UpdatedAt:           feed.UpdatedAt,
+		Name:                feed.Name,
+		Url:                 feed.Url,
+		UserID:              feed.UserID,
+		LastFetchedAt:       feed.LastFetchedAt,
+		RetentionMaxAgeDays: feed.RetentionMaxAgeDays,
+		RetentionMaxPosts:   feed.RetentionMaxPosts,
+		SiteTitle:           feed.SiteTitle,
+		SiteDescription:     feed.SiteDescription,
+		SiteLink:            feed.SiteLink,
+		Language:            feed.Language,
+		ImageUrl:            feed.ImageUrl,
+		Generator:           feed.Generator,
+		DeadAt:              feed.DeadAt,
+		NextFetchAt:         feed.NextFetchAt,
+		ParseWarning:        feed.ParseWarning,
+		FetchFullContent:    feed.FetchFullContent,
+		UserName:            user.Name,
+	}, nil
+}
+
+func (m *Memory) GetFeedsWithUserNames(ctx context.Context) ([]database.GetFeedsWithUserNamesRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []database.GetFeedsWithUserNamesRow
+	for _, feed := range m.t.feeds {
+		user, ok := m.t.user(feed.UserID)
+		if !ok {
+			continue
+		}
+		items = append(items, database.GetFeedsWithUserNamesRow{
+			ID:        feed.ID,
+			CreatedAt: feed.CreatedAt,
+			UpdatedAt: feed.UpdatedAt,
+			FeedName:  feed.Name,
+			Url:       feed.Url,
+			SiteTitle: feed.SiteTitle,
+			SiteLink:  feed.SiteLink,
+			UserName:  user.Name,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var items []database.Feed
+	for _, feed := range m.t.feeds {
+		if feed.DeadAt.Valid || (feed.NextFetchAt.Valid && feed.NextFetchAt.Time.After(now)) {
+			continue
+		}
+		items = append(items, feed)
+	}
+
+	// Feeds that were never fetched come first, then the least recently fetched
+	slices.SortStableFunc(items, func(a, b database.Feed) int {
+		switch {
+		case a.LastFetchedAt.Valid != b.LastFetchedAt.Valid:
+			if !a.LastFetchedAt.Valid {
+				return -1
+			}
+			return 1
+		case a.LastFetchedAt.Valid && !a.LastFetchedAt.Time.Equal(b.LastFetchedAt.Time):
+			return a.LastFetchedAt.Time.Compare(b.LastFetchedAt.Time)
+		}
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	})
+	if len(items) > int(limit) {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// updateFeed applies change to the feed with the given ID, if there is one
+func (m *Memory) updateFeed(id uuid.UUID, change func(*database.Feed)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if feed, ok := m.t.feed(id); ok {
+		change(feed)
+	}
+	return nil
+}
+
+func (m *Memory) MarkFeedDead(ctx context.Context, id uuid.UUID) error {
+	return m.updateFeed(id, func(feed *database.Feed) {
+		feed.DeadAt = sql.NullTime{Time: time.Now(), Valid: true}
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	return m.updateFeed(id, func(feed *database.Feed) {
+		feed.LastFetchedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) ReviveFeed(ctx context.Context, id uuid.UUID) error {
+	return m.updateFeed(id, func(feed *database.Feed) {
+		feed.DeadAt = sql.NullTime{}
+		feed.NextFetchAt = sql.NullTime{}
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) SetFeedFetchFullContent(ctx context.Context, arg database.SetFeedFetchFullContentParams) error {
+	return m.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.FetchFullContent = arg.FetchFullContent
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) SetFeedParseWarning(ctx context.Context, arg database.SetFeedParseWarningParams) error {
+	return m.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.ParseWarning = arg.ParseWarning
+	})
+}
+
+func (m *Memory) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	return m.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.RetentionMaxAgeDays = arg.RetentionMaxAgeDays
+		feed.RetentionMaxPosts = arg.RetentionMaxPosts
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	return m.updateFeed(arg.ID, func(feed *database.Feed) {
+		feed.SiteTitle = arg.SiteTitle
+		feed.SiteDescription = arg.SiteDescription
+		feed.SiteLink = arg.SiteLink
+		feed.Language = arg.Language
+		feed.ImageUrl = arg.ImageUrl
+		feed.Generator = arg.Generator
+		feed.UpdatedAt = time.Now()
+	})
+}
+
+func (m *Memory) UpdateFeedUrl(ctx context.Context, arg database.UpdateFeedUrlParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, feed := range m.t.feeds {
+		if feed.Url == arg.Url && feed.ID != arg.ID {
+			return conflict("feed %s", arg.Url)
+		}
+	}
+	if feed, ok := m.t.feed(arg.ID); ok {
+		feed.Url = arg.Url
+		feed.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *Memory) CreateFeedAlias(ctx context.Context, arg database.CreateFeedAliasParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.feed(arg.FeedID); !ok {
+		return fmt.Errorf("feed %s does not exist", arg.FeedID)
+	}
+	for i := range m.t.aliases {
+		if m.t.aliases[i].Url == arg.Url {
+			m.t.aliases[i].FeedID = arg.FeedID
+			return nil
+		}
+	}
+	m.t.aliases = append(m.t.aliases, database.FeedAlias{Url: arg.Url, FeedID: arg.FeedID, CreatedAt: arg.CreatedAt})
+	return nil
+}
+
+func (m *Memory) DeleteFeedAlias(ctx context.Context, url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.t.aliases = slices.DeleteFunc(m.t.aliases, func(a database.FeedAlias) bool { return a.Url == url })
+	return nil
+}
+
+func (m *Memory) MoveFeedAliases(ctx context.Context, arg database.MoveFeedAliasesParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.t.aliases {
+		if m.t.aliases[i].FeedID == arg.FromFeedID {
+			m.t.aliases[i].FeedID = arg.ToFeedID
+		}
+	}
+	return nil
+}
+
+func (m *Memory) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.t.user(arg.UserID)
+	if !ok {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	feed, ok := m.t.feed(arg.FeedID)
+	if !ok {
+		return database.CreateFeedFollowRow{}, fmt.Errorf("feed %s does not exist", arg.FeedID)
+	}
+	if m.t.isFollowing(arg.UserID, arg.FeedID) {
+		return database.CreateFeedFollowRow{}, conflict("%s already follows %s", user.Name, feed.Url)
+	}
+	m.t.follows = append(m.t.follows, database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	})
+	return database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (m *Memory) DeleteFeedFollowByUserAndUrl(ctx context.Context, arg database.DeleteFeedFollowByUserAndUrlParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	feed, ok := m.t.feedByURL(arg.Url)
+	if !ok {
+		return nil
+	}
+	feedID := feed.ID
+	m.t.follows = slices.DeleteFunc(m.t.follows, func(f database.FeedFollow) bool {
+		return f.UserID == arg.UserID && f.FeedID == feedID
+	})
+	return nil
+}
+
+func (m *Memory) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []database.GetFeedFollowsForUserRow
+	for _, follow := range m.t.follows {
+		if follow.UserID != userID {
+			continue
+		}
+		feed, ok := m.t.feed(follow.FeedID)
+		if !ok {
+			continue
+		}
+		user, ok := m.t.user(follow.UserID)
+		if !ok {
+			continue
+		}
+		items = append(items, database.GetFeedFollowsForUserRow{
+			ID:        follow.ID,
+			CreatedAt: follow.CreatedAt,
+			UpdatedAt: follow.UpdatedAt,
+			UserID:    follow.UserID,
+			FeedID:    follow.FeedID,
+			FeedName:  feed.Name,
+			FeedUrl:   feed.Url,
+			UserName:  user.Name,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Copy the follows, leaving out users who already follow the target feed
+	for _, follow := range slices.Clone(m.t.follows) {
+		if follow.FeedID != arg.FromFeedID || m.t.isFollowing(follow.UserID, arg.ToFeedID) {
+			continue
+		}
+		m.t.follows = append(m.t.follows, database.FeedFollow{
+			ID:        uuid.New(),
+			CreatedAt: follow.CreatedAt,
+			UpdatedAt: time.Now(),
+			UserID:    follow.UserID,
+			FeedID:    arg.ToFeedID,
+		})
+	}
+	return nil
+}
+
+func (m *Memory) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.feed(arg.FeedID); !ok {
+		return database.Post{}, fmt.Errorf("feed %s does not exist", arg.FeedID)
+	}
+	for _, post := range m.t.posts {
+		if post.ID == arg.ID || post.Url == arg.Url {
+			return database.Post{}, conflict("post %s", arg.Url)
+		}
+	}
+	categories := slices.Clone(arg.Categories)
+	if categories == nil {
+		return database.Post{}, fmt.Errorf("post categories must not be null")
+	}
+	post := database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Author:      arg.Author,
+		Categories:  categories,
+	}
+	m.t.posts = append(m.t.posts, post)
+	return copyPost(post), nil
+}
+
+func (m *Memory) DeleteExcessPosts(ctx context.Context, defaultMaxPosts int32) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Rank each feed's unsaved posts from newest to oldest
+	ranked := make(map[uuid.UUID][]database.Post)
+	for _, post := range m.t.posts {
+		if !m.t.isSavedByAnyone(post.ID) {
+			ranked[post.FeedID] = append(ranked[post.FeedID], post)
+		}
+	}
+	excess := make(map[uuid.UUID]bool)
+	for feedID, posts := range ranked {
+		feed, ok := m.t.feed(feedID)
+		if !ok {
+			continue
+		}
+		maxPosts := defaultMaxPosts
+		if feed.RetentionMaxPosts.Valid {
+			maxPosts = feed.RetentionMaxPosts.Int32
+		}
+		if maxPosts <= 0 || len(posts) <= int(maxPosts) {
+			continue
+		}
+		slices.SortStableFunc(posts, func(a, b database.Post) int {
+			switch {
+			case a.PublishedAt.Valid != b.PublishedAt.Valid:
+				if a.PublishedAt.Valid {
+					return -1
+				}
+				return 1
+			case a.PublishedAt.Valid && !a.PublishedAt.Time.Equal(b.PublishedAt.Time):
+				return b.PublishedAt.Time.Compare(a.PublishedAt.Time)
+			}
+			return b.CreatedAt.Compare(a.CreatedAt)
+		})
+		for _, post := range posts[maxPosts:] {
+			excess[post.ID] = true
+		}
+	}
+	return m.t.deletePosts(func(p database.Post) bool { return excess[p.ID] }), nil
+}
+
+func (m *Memory) DeleteExpiredPosts(ctx context.Context, defaultMaxAgeDays int32) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	return m.t.deletePosts(func(p database.Post) bool {
+		feed, ok := m.t.feed(p.FeedID)
+		if !ok || m.t.isSavedByAnyone(p.ID) {
+			return false
+		}
+		maxAgeDays := defaultMaxAgeDays
+		if feed.RetentionMaxAgeDays.Valid {
+			maxAgeDays = feed.RetentionMaxAgeDays.Int32
+		}
+		if maxAgeDays <= 0 {
+			return false
+		}
+		date := p.CreatedAt
+		if p.PublishedAt.Valid {
+			date = p.PublishedAt.Time
+		}
+		return date.Before(now.AddDate(0, 0, -int(maxAgeDays)))
+	}), nil
+}
+
+func (t *tables) isSavedByAnyone(postID uuid.UUID) bool {
+	for _, saved := range t.saved {
+		if saved.PostID == postID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) GetPostByUrl(ctx context.Context, url string) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, post := range m.t.posts {
+		if post.Url == url {
+			return copyPost(post), nil
+		}
+	}
+	return database.Post{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetPostListForUser(ctx context.Context, arg database.GetPostListForUserParams) ([]database.GetPostListForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var posts []database.Post
+	for _, post := range m.t.posts {
+		if arg.FeedID.Valid && post.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		if m.t.isFollowing(arg.UserID, post.FeedID) {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortStableFunc(posts, newestFirst)
+	if len(posts) > int(arg.RowLimit) {
+		posts = posts[:max(arg.RowLimit, 0)]
+	}
+
+	var items []database.GetPostListForUserRow
+	for _, post := range posts {
+		feed, _ := m.t.feed(post.FeedID)
+		items = append(items, database.GetPostListForUserRow{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			Author:      post.Author,
+			Categories:  slices.Clone(post.Categories),
+			FeedName:    feed.Name,
+			IsRead:      m.t.isRead(arg.UserID, post.ID),
+			IsSaved:     m.t.isSaved(arg.UserID, post.ID),
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) GetPostsByFeedID(ctx context.Context, feedID uuid.UUID) ([]database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []database.Post
+	for _, post := range m.t.posts {
+		if post.FeedID == feedID {
+			items = append(items, copyPost(post))
+		}
+	}
+	return items, nil
+}
+
+// GetPostsByIDPrefix supports the only LIKE pattern gator uses, a prefix of
+// the post ID followed by %
+func (m *Memory) GetPostsByIDPrefix(ctx context.Context, idPrefix string) ([]database.GetPostsByIDPrefixRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prefix := strings.TrimSuffix(idPrefix, "%")
+	var posts []database.Post
+	for _, post := range m.t.posts {
+		if strings.HasPrefix(post.ID.String(), prefix) {
+			posts = append(posts, post)
+		}
+	}
+	slices.SortFunc(posts, func(a, b database.Post) int { return bytes.Compare(a.ID[:], b.ID[:]) })
+	if len(posts) > 2 {
+		posts = posts[:2]
+	}
+
+	var items []database.GetPostsByIDPrefixRow
+	for _, post := range posts {
+		feed, _ := m.t.feed(post.FeedID)
+		items = append(items, database.GetPostsByIDPrefixRow{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			Author:      post.Author,
+			Categories:  slices.Clone(post.Categories),
+			FeedName:    feed.Name,
+			FeedUrl:     feed.Url,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var posts []database.Post
+	for _, post := range m.t.posts {
+		if m.t.isFollowing(arg.UserID, post.FeedID) {
+			posts = append(posts, copyPost(post))
+		}
+	}
+	slices.SortStableFunc(posts, newestFirst)
+	start := min(max(int(arg.Offset), 0), len(posts))
+	end := min(start+max(int(arg.Limit), 0), len(posts))
+	return posts[start:end], nil
+}
+
+func (m *Memory) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []database.GetUnreadCountsForUserRow
+	counts := make(map[uuid.UUID]int)
+	for _, post := range m.t.posts {
+		if !m.t.isFollowing(userID, post.FeedID) || m.t.isRead(userID, post.ID) {
+			continue
+		}
+		i, ok := counts[post.FeedID]
+		if !ok {
+			i = len(items)
+			counts[post.FeedID] = i
+			items = append(items, database.GetUnreadCountsForUserRow{FeedID: post.FeedID})
+		}
+		items[i].Unread++
+	}
+	return items, nil
+}
+
+func (m *Memory) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.user(arg.UserID); !ok {
+		return fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	if _, ok := m.t.post(arg.PostID); !ok {
+		return fmt.Errorf("post %s does not exist", arg.PostID)
+	}
+	if !m.t.isRead(arg.UserID, arg.PostID) {
+		m.t.reads = append(m.t.reads, database.PostRead{UserID: arg.UserID, PostID: arg.PostID, ReadAt: arg.ReadAt})
+	}
+	return nil
+}
+
+func (m *Memory) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.t.reads)
+	m.t.reads = slices.DeleteFunc(m.t.reads, func(r database.PostRead) bool {
+		return r.UserID == arg.UserID && r.PostID == arg.PostID
+	})
+	return int64(before - len(m.t.reads)), nil
+}
+
+func (m *Memory) MoveFeedPosts(ctx context.Context, arg database.MoveFeedPostsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.t.posts {
+		if m.t.posts[i].FeedID == arg.FromFeedID {
+			m.t.posts[i].FeedID = arg.ToFeedID
+			m.t.posts[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *Memory) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if post, ok := m.t.post(arg.ID); ok {
+		post.Content = arg.Content
+		post.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (m *Memory) CreatePostEnclosure(ctx context.Context, arg database.CreatePostEnclosureParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.post(arg.PostID); !ok {
+		return fmt.Errorf("post %s does not exist", arg.PostID)
+	}
+	m.t.enclosures = append(m.t.enclosures, database.PostEnclosure{
+		ID:       arg.ID,
+		PostID:   arg.PostID,
+		Url:      arg.Url,
+		MimeType: arg.MimeType,
+		Length:   arg.Length,
+	})
+	return nil
+}
+
+func (m *Memory) GetPostEnclosures(ctx context.Context, postID uuid.UUID) ([]database.PostEnclosure, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []database.PostEnclosure
+	for _, enclosure := range m.t.enclosures {
+		if enclosure.PostID == postID {
+			items = append(items, enclosure)
+		}
+	}
+	return items, nil
+}
+
+func (m *Memory) SavePost(ctx context.Context, arg database.SavePostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.user(arg.UserID); !ok {
+		return fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	if _, ok := m.t.post(arg.PostID); !ok {
+		return fmt.Errorf("post %s does not exist", arg.PostID)
+	}
+	if !m.t.isSaved(arg.UserID, arg.PostID) {
+		m.t.saved = append(m.t.saved, database.SavedPost{UserID: arg.UserID, PostID: arg.PostID, CreatedAt: arg.CreatedAt})
+	}
+	return nil
+}
+
+func (m *Memory) UnsavePost(ctx context.Context, arg database.UnsavePostParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.t.saved)
+	m.t.saved = slices.DeleteFunc(m.t.saved, func(s database.SavedPost) bool {
+		return s.UserID == arg.UserID && s.PostID == arg.PostID
+	})
+	return int64(before - len(m.t.saved)), nil
+}
+
+func (m *Memory) CreateUserFilter(ctx context.Context, arg database.CreateUserFilterParams) (database.UserFilter, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.t.user(arg.UserID); !ok {
+		return database.UserFilter{}, fmt.Errorf("user %s does not exist", arg.UserID)
+	}
+	if _, ok := m.t.feed(arg.FeedID.UUID); arg.FeedID.Valid && !ok {
+		return database.UserFilter{}, fmt.Errorf("feed %s does not exist", arg.FeedID.UUID)
+	}
+	filter := database.UserFilter{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		Action:    arg.Action,
+		Field:     arg.Field,
+		Pattern:   arg.Pattern,
+		IsRegex:   arg.IsRegex,
+		FeedID:    arg.FeedID,
+	}
+	m.t.filters = append(m.t.filters, filter)
+	return filter, nil
+}
+
+func (m *Memory) DeleteUserFilter(ctx context.Context, arg database.DeleteUserFilterParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	before := len(m.t.filters)
+	m.t.filters = slices.DeleteFunc(m.t.filters, func(f database.UserFilter) bool {
+		return f.ID == arg.ID && f.UserID == arg.UserID
+	})
+	return int64(before - len(m.t.filters)), nil
+}
+
+func (m *Memory) GetUserFiltersForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUserFiltersForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var items []database.GetUserFiltersForUserRow
+	for _, filter := range m.t.filters {
+		if filter.UserID != userID {
+			continue
+		}
+		feedURL := sql.NullString{}
+		if feed, ok := m.t.feed(filter.FeedID.UUID); filter.FeedID.Valid && ok {
+			feedURL = sql.NullString{String: feed.Url, Valid: true}
+		}
+		items = append(items, database.GetUserFiltersForUserRow{
+			ID:        filter.ID,
+			CreatedAt: filter.CreatedAt,
+			UpdatedAt: filter.UpdatedAt,
+			UserID:    filter.UserID,
+			Action:    filter.Action,
+			Field:     filter.Field,
+			Pattern:   filter.Pattern,
+			IsRegex:   filter.IsRegex,
+			FeedID:    filter.FeedID,
+			FeedUrl:   feedURL,
+		})
+	}
+	slices.SortStableFunc(items, func(a, b database.GetUserFiltersForUserRow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return items, nil
+}
+
+func (m *Memory) MoveFeedFilters(ctx context.Context, arg database.MoveFeedFiltersParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !arg.FromFeedID.Valid {
+		return nil
+	}
+	for i := range m.t.filters {
+		if m.t.filters[i].FeedID == arg.FromFeedID {
+			m.t.filters[i].FeedID = arg.ToFeedID
+			m.t.filters[i].UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
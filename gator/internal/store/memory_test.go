@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMemoryInTxKeepsConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	started := make(chan struct{})
+	written := make(chan error)
+	go func() {
+		<-started
+		_, err := m.CreateUser(ctx, newUser("carol"))
+		written <- err
+	}()
+
+	err := m.InTx(ctx, func(q Store) error {
+		close(started)
+		// Give the concurrent writer time to run while the transaction is open
+		time.Sleep(20 * time.Millisecond)
+		return errors.New("rollback")
+	})
+	if err == nil {
+		t.Fatalf("InTx succeeded, want the rollback error")
+	}
+	if err := <-written; err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if got := userNames(t, m); !slices.Equal(got, []string{"carol"}) {
+		t.Errorf("users = %v, want [carol]", got)
+	}
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+// Postgres stores everything in a PostgreSQL database through the queries
+// generated by sqlc
+type Postgres struct {
+	*database.Queries
+	db *sql.DB
+}
+
+var _ Store = (*Postgres)(nil)
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{Queries: database.New(db), db: db}
+}
+
+func (p *Postgres) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	post, err := p.Queries.CreatePost(ctx, arg)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+		return post, fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return post, err
+}
+
+func (p *Postgres) InTx(ctx context.Context, fn func(Store) error) error {
+	if p.db == nil {
+		return fmt.Errorf("nested transactions are not supported")
+	}
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&Postgres{Queries: p.Queries.WithTx(tx)}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,1115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/database"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLite stores everything in a single SQLite database file. The queries
+// mirror the PostgreSQL ones in sql/queries.
+//
+// Timestamps are written in UTC so that comparing and sorting them as text,
+// which is what SQLite does, matches their order in time.
+type SQLite struct {
+	db database.DBTX
+	// conn is nil inside a transaction
+	conn *sql.DB
+}
+
+var _ Store = (*SQLite)(nil)
+
+// OpenSQLite opens the database file at path, creating it if needed, with
+// the settings the SQLite store relies on: enforced foreign keys for the
+// cascading deletes and timestamps written in a sortable format
+func OpenSQLite(path string) (*sql.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer, so agg's concurrent fetches take turns
+	// on one connection instead of failing with "database is locked"
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{db: db, conn: db}
+}
+
+func (s *SQLite) InTx(ctx context.Context, fn func(Store) error) error {
+	if s.conn == nil {
+		return fmt.Errorf("nested transactions are not supported")
+	}
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLite{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// utc converts a timestamp parameter to UTC, see SQLite
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+func nullUTC(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+	return sql.NullTime{Time: t.Time.UTC(), Valid: true}
+}
+
+// stringList stores a list of strings as a JSON array
+type stringList []string
+
+func (l stringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
+
+func (l *stringList) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), l)
+	case []byte:
+		return json.Unmarshal(src, l)
+	}
+	return fmt.Errorf("cannot scan %T into a list of strings", src)
+}
+
+// isUniqueViolation reports whether an insert failed on a unique key
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+const feedColumns = `feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.retention_max_age_days, feeds.retention_max_posts, feeds.site_title, feeds.site_description, feeds.site_link, feeds.language, feeds.image_url, feeds.generator, feeds.dead_at, feeds.next_fetch_at, feeds.parse_warning, feeds.fetch_full_content`
+
+func scanFeed(row scanner, extra ...any) (database.Feed, error) {
+	var i database.Feed
+	err := row.Scan(append([]any{
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.RetentionMaxAgeDays,
+		&i.RetentionMaxPosts,
+		&i.SiteTitle,
+		&i.SiteDescription,
+		&i.SiteLink,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.DeadAt,
+		&i.NextFetchAt,
+		&i.ParseWarning,
+		&i.FetchFullContent,
+	}, extra...)...)
+	return i, err
+}
+
+const postColumns = `posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.author, posts.categories`
+
+func scanPost(row scanner, extra ...any) (database.Post, error) {
+	var i database.Post
+	err := row.Scan(append([]any{
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.Author,
+		(*stringList)(&i.Categories),
+	}, extra...)...)
+	return i, err
+}
+
+// queryPosts runs a query that selects postColumns
+func (s *SQLite) queryPosts(ctx context.Context, query string, args ...any) ([]database.Post, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Post
+	for rows.Next() {
+		i, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// exec runs a statement and returns the number of rows it changed
+func (s *SQLite) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createUser = `INSERT INTO users (id, created_at, updated_at, name, password_hash)
+VALUES (?, ?, ?, ?, ?)`
+
+func (s *SQLite) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	_, err := s.db.ExecContext(ctx, createUser, arg.ID, utc(arg.CreatedAt), utc(arg.UpdatedAt), arg.Name, arg.PasswordHash)
+	if err != nil {
+		return database.User{}, err
+	}
+	return database.User{
+		ID:           arg.ID,
+		CreatedAt:    arg.CreatedAt,
+		UpdatedAt:    arg.UpdatedAt,
+		Name:         arg.Name,
+		PasswordHash: arg.PasswordHash,
+	}, nil
+}
+
+const deleteAllUsers = `DELETE FROM users`
+
+func (s *SQLite) DeleteAllUsers(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, deleteAllUsers)
+	return err
+}
+
+const getUserByName = `SELECT id, created_at, updated_at, name, password_hash
+FROM users
+WHERE name = ?`
+
+func (s *SQLite) GetUserByName(ctx context.Context, name string) (database.User, error) {
+	var i database.User
+	err := s.db.QueryRowContext(ctx, getUserByName, name).Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt, &i.Name, &i.PasswordHash)
+	return i, err
+}
+
+const getUsers = `SELECT id, created_at, updated_at, name, password_hash
+FROM users`
+
+func (s *SQLite) GetUsers(ctx context.Context) ([]database.User, error) {
+	rows, err := s.db.QueryContext(ctx, getUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.User
+	for rows.Next() {
+		var i database.User
+		if err := rows.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt, &i.Name, &i.PasswordHash); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserPassword = `UPDATE users
+SET password_hash = ?, updated_at = ?
+WHERE id = ?`
+
+func (s *SQLite) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	_, err := s.db.ExecContext(ctx, setUserPassword, arg.PasswordHash, utc(arg.UpdatedAt), arg.ID)
+	return err
+}
+
+const createSession = `INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+VALUES (?, ?, ?, ?)`
+
+func (s *SQLite) CreateSession(ctx context.Context, arg database.CreateSessionParams) error {
+	now := time.Now()
+	lifetime := time.Duration(arg.LifetimeSeconds * float64(time.Second))
+	_, err := s.db.ExecContext(ctx, createSession, arg.TokenHash, arg.UserID, utc(now), utc(now.Add(lifetime)))
+	return err
+}
+
+const deleteExpiredSessions = `DELETE FROM sessions
+WHERE expires_at <= ?`
+
+func (s *SQLite) DeleteExpiredSessions(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, deleteExpiredSessions, utc(time.Now()))
+	return err
+}
+
+const deleteSession = `DELETE FROM sessions
+WHERE token_hash = ?`
+
+func (s *SQLite) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := s.db.ExecContext(ctx, deleteSession, tokenHash)
+	return err
+}
+
+const deleteUserSessions = `DELETE FROM sessions
+WHERE user_id = ?`
+
+func (s *SQLite) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, deleteUserSessions, userID)
+	return err
+}
+
+const getSession = `SELECT token_hash, user_id, created_at, expires_at
+FROM sessions
+WHERE token_hash = ?
+AND expires_at > ?`
+
+func (s *SQLite) GetSession(ctx context.Context, tokenHash string) (database.Session, error) {
+	var i database.Session
+	err := s.db.QueryRowContext(ctx, getSession, tokenHash, utc(time.Now())).Scan(&i.TokenHash, &i.UserID, &i.CreatedAt, &i.ExpiresAt)
+	return i, err
+}
+
+const createFeed = `INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?, ?, ?, ?, ?, ?)`
+
+func (s *SQLite) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.CreateFeedRow, error) {
+	_, err := s.db.ExecContext(ctx, createFeed,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Name,
+		arg.Url,
+		arg.UserID,
+	)
+	if err != nil {
+		return database.CreateFeedRow{}, err
+	}
+	return database.CreateFeedRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}, nil
+}
+
+const deferFeed = `UPDATE feeds
+SET next_fetch_at = ?2, updated_at = ?3
+WHERE id = ?1`
+
+func (s *SQLite) DeferFeed(ctx context.Context, arg database.DeferFeedParams) error {
+	now := time.Now()
+	delay := time.Duration(arg.DelaySeconds * float64(time.Second))
+	_, err := s.db.ExecContext(ctx, deferFeed, arg.ID, utc(now.Add(delay)), utc(now))
+	return err
+}
+
+const deleteFeed = `DELETE FROM feeds
+WHERE id = ?`
+
+func (s *SQLite) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const getFeedByUrl = `SELECT id, created_at, updated_at, name, url, user_id
+FROM feeds
+WHERE url = ?1
+OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = ?1)`
+
+func (s *SQLite) GetFeedByUrl(ctx context.Context, url string) (database.GetFeedByUrlRow, error) {
+	var i database.GetFeedByUrlRow
+	err := s.db.QueryRowContext(ctx, getFeedByUrl, url).Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getFeedInfoByUrl = `SELECT ` + feedColumns + `, users.name AS user_name
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.url = ?1
+OR feeds.id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = ?1)`
+
+func (s *SQLite) GetFeedInfoByUrl(ctx context.Context, url string) (database.GetFeedInfoByUrlRow, error) {
+	var userName string
+	feed, err := scanFeed(s.db.QueryRowContext(ctx, getFeedInfoByUrl, url), &userName)
+	if err != nil {
+		return database.GetFeedInfoByUrlRow{}, err
+	}
+	return database.GetFeedInfoByUrlRow{
+		ID:                  feed.ID,
+		CreatedAt:           feed.CreatedAt,
+		UpdatedAt:           feed.UpdatedAt,
+		Name:                feed.Name,
+		Url:                 feed.Url,
+		UserID:              feed.UserID,
+		LastFetchedAt:       feed.LastFetchedAt,
+		RetentionMaxAgeDays: feed.RetentionMaxAgeDays,
+		RetentionMaxPosts:   feed.RetentionMaxPosts,
+		SiteTitle:           feed.SiteTitle,
+		SiteDescription:     feed.SiteDescription,
+		SiteLink:            feed.SiteLink,
+		Language:            feed.Language,
+		ImageUrl:            feed.ImageUrl,
+		Generator:           feed.Generator,
+		DeadAt:              feed.DeadAt,
+		NextFetchAt:         feed.NextFetchAt,
+		ParseWarning:        feed.ParseWarning,
+		FetchFullContent:    feed.FetchFullContent,
+		UserName:            userName,
+	}, nil
+}
+
+const getFeedsWithUserNames = `SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name AS feed_name, feeds.url, feeds.site_title, feeds.site_link, users.name AS user_name
+FROM feeds
+JOIN users ON feeds.user_id = users.id`
+
+func (s *SQLite) GetFeedsWithUserNames(ctx context.Context) ([]database.GetFeedsWithUserNamesRow, error) {
+	rows, err := s.db.QueryContext(ctx, getFeedsWithUserNames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedsWithUserNamesRow
+	for rows.Next() {
+		var i database.GetFeedsWithUserNamesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FeedName,
+			&i.Url,
+			&i.SiteTitle,
+			&i.SiteLink,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNextFeedsToFetch = `SELECT ` + feedColumns + `
+FROM feeds
+WHERE dead_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= ?)
+ORDER BY last_fetched_at NULLS FIRST, updated_at
+LIMIT ?`
+
+func (s *SQLite) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]database.Feed, error) {
+	rows, err := s.db.QueryContext(ctx, getNextFeedsToFetch, utc(time.Now()), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.Feed
+	for rows.Next() {
+		i, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedDead = `UPDATE feeds
+SET dead_at = ?2, updated_at = ?2
+WHERE id = ?1`
+
+func (s *SQLite) MarkFeedDead(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, markFeedDead, id, utc(time.Now()))
+	return err
+}
+
+const markFeedFetched = `UPDATE feeds
+SET last_fetched_at = ?2, updated_at = ?2
+WHERE id = ?1`
+
+func (s *SQLite) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, markFeedFetched, id, utc(time.Now()))
+	return err
+}
+
+const reviveFeed = `UPDATE feeds
+SET dead_at = NULL, next_fetch_at = NULL, updated_at = ?2
+WHERE id = ?1`
+
+func (s *SQLite) ReviveFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, reviveFeed, id, utc(time.Now()))
+	return err
+}
+
+const setFeedFetchFullContent = `UPDATE feeds
+SET fetch_full_content = ?, updated_at = ?
+WHERE id = ?`
+
+func (s *SQLite) SetFeedFetchFullContent(ctx context.Context, arg database.SetFeedFetchFullContentParams) error {
+	_, err := s.db.ExecContext(ctx, setFeedFetchFullContent, arg.FetchFullContent, utc(time.Now()), arg.ID)
+	return err
+}
+
+const setFeedParseWarning = `UPDATE feeds
+SET parse_warning = ?
+WHERE id = ?`
+
+func (s *SQLite) SetFeedParseWarning(ctx context.Context, arg database.SetFeedParseWarningParams) error {
+	_, err := s.db.ExecContext(ctx, setFeedParseWarning, arg.ParseWarning, arg.ID)
+	return err
+}
+
+const setFeedRetention = `UPDATE feeds
+SET retention_max_age_days = ?, retention_max_posts = ?, updated_at = ?
+WHERE id = ?`
+
+func (s *SQLite) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	_, err := s.db.ExecContext(ctx, setFeedRetention,
+		arg.RetentionMaxAgeDays,
+		arg.RetentionMaxPosts,
+		utc(time.Now()),
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedMetadata = `UPDATE feeds
+SET site_title = ?, site_description = ?, site_link = ?, language = ?, image_url = ?, generator = ?, updated_at = ?
+WHERE id = ?`
+
+func (s *SQLite) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	_, err := s.db.ExecContext(ctx, updateFeedMetadata,
+		arg.SiteTitle,
+		arg.SiteDescription,
+		arg.SiteLink,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+		utc(time.Now()),
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedUrl = `UPDATE feeds
+SET url = ?, updated_at = ?
+WHERE id = ?`
+
+func (s *SQLite) UpdateFeedUrl(ctx context.Context, arg database.UpdateFeedUrlParams) error {
+	_, err := s.db.ExecContext(ctx, updateFeedUrl, arg.Url, utc(time.Now()), arg.ID)
+	return err
+}
+
+const createFeedAlias = `INSERT INTO feed_aliases (url, feed_id, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (url) DO UPDATE SET feed_id = excluded.feed_id`
+
+func (s *SQLite) CreateFeedAlias(ctx context.Context, arg database.CreateFeedAliasParams) error {
+	_, err := s.db.ExecContext(ctx, createFeedAlias, arg.Url, arg.FeedID, utc(arg.CreatedAt))
+	return err
+}
+
+const deleteFeedAlias = `DELETE FROM feed_aliases
+WHERE url = ?`
+
+func (s *SQLite) DeleteFeedAlias(ctx context.Context, url string) error {
+	_, err := s.db.ExecContext(ctx, deleteFeedAlias, url)
+	return err
+}
+
+const moveFeedAliases = `UPDATE feed_aliases
+SET feed_id = ?
+WHERE feed_id = ?`
+
+func (s *SQLite) MoveFeedAliases(ctx context.Context, arg database.MoveFeedAliasesParams) error {
+	_, err := s.db.ExecContext(ctx, moveFeedAliases, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const createFeedFollow = `INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (?, ?, ?, ?, ?)`
+
+const getFeedFollowNames = `SELECT feeds.name, users.name
+FROM feeds, users
+WHERE feeds.id = ? AND users.id = ?`
+
+func (s *SQLite) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	_, err := s.db.ExecContext(ctx, createFeedFollow,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	i := database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	err = s.db.QueryRowContext(ctx, getFeedFollowNames, arg.FeedID, arg.UserID).Scan(&i.FeedName, &i.UserName)
+	return i, err
+}
+
+const deleteFeedFollowByUserAndUrl = `DELETE FROM feed_follows
+WHERE user_id = ?1
+AND feed_id IN (
+    SELECT id FROM feeds
+    WHERE url = ?2 OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = ?2)
+)`
+
+func (s *SQLite) DeleteFeedFollowByUserAndUrl(ctx context.Context, arg database.DeleteFeedFollowByUserAndUrlParams) error {
+	_, err := s.db.ExecContext(ctx, deleteFeedFollowByUserAndUrl, arg.UserID, arg.Url)
+	return err
+}
+
+const getFeedFollowsForUser = `SELECT
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url,
+    users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.user_id = ?`
+
+func (s *SQLite) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := s.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetFeedFollowsForUserRow
+	for rows.Next() {
+		var i database.GetFeedFollowsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.FeedID,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFollowersOfFeed = `SELECT user_id, created_at
+FROM feed_follows
+WHERE feed_id = ?`
+
+const copyFeedFollow = `INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (user_id, feed_id) DO NOTHING`
+
+// MoveFeedFollows copies the follows one at a time because SQLite cannot
+// generate the new IDs
+func (s *SQLite) MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error {
+	rows, err := s.db.QueryContext(ctx, getFollowersOfFeed, arg.FromFeedID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var follows []database.FeedFollow
+	for rows.Next() {
+		var i database.FeedFollow
+		if err := rows.Scan(&i.UserID, &i.CreatedAt); err != nil {
+			return err
+		}
+		follows = append(follows, i)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, follow := range follows {
+		_, err := s.db.ExecContext(ctx, copyFeedFollow,
+			uuid.New(),
+			utc(follow.CreatedAt),
+			utc(time.Now()),
+			follow.UserID,
+			arg.ToFeedID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const createPost = `INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func (s *SQLite) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	_, err := s.db.ExecContext(ctx, createPost,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		nullUTC(arg.PublishedAt),
+		arg.FeedID,
+		arg.Author,
+		stringList(arg.Categories),
+	)
+	if isUniqueViolation(err) {
+		return database.Post{}, fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	if err != nil {
+		return database.Post{}, err
+	}
+	return database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Author:      arg.Author,
+		Categories:  arg.Categories,
+	}, nil
+}
+
+const deleteExcessPosts = `DELETE FROM posts
+WHERE posts.id IN (
+    SELECT ranked.id
+    FROM (
+        SELECT
+            posts.id,
+            ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC) AS position,
+            COALESCE(feeds.retention_max_posts, ?1) AS max_posts
+        FROM posts
+        JOIN feeds ON posts.feed_id = feeds.id
+        WHERE NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+    ) AS ranked
+    WHERE ranked.max_posts > 0 AND ranked.position > ranked.max_posts
+)`
+
+func (s *SQLite) DeleteExcessPosts(ctx context.Context, defaultMaxPosts int32) (int64, error) {
+	return s.exec(ctx, deleteExcessPosts, defaultMaxPosts)
+}
+
+const deleteExpiredPosts = `DELETE FROM posts
+WHERE posts.id IN (
+    SELECT posts.id
+    FROM posts
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE COALESCE(feeds.retention_max_age_days, ?1) > 0
+    AND julianday(COALESCE(posts.published_at, posts.created_at)) < julianday('now') - COALESCE(feeds.retention_max_age_days, ?1)
+    AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+)`
+
+func (s *SQLite) DeleteExpiredPosts(ctx context.Context, defaultMaxAgeDays int32) (int64, error) {
+	return s.exec(ctx, deleteExpiredPosts, defaultMaxAgeDays)
+}
+
+const getPostByUrl = `SELECT ` + postColumns + `
+FROM posts
+WHERE url = ?`
+
+func (s *SQLite) GetPostByUrl(ctx context.Context, url string) (database.Post, error) {
+	return scanPost(s.db.QueryRowContext(ctx, getPostByUrl, url))
+}
+
+const getPostListForUser = `SELECT
+    ` + postColumns + `,
+    feeds.name AS feed_name,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM saved_posts
+        WHERE saved_posts.post_id = posts.id AND saved_posts.user_id = feed_follows.user_id
+    ) AS is_saved
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = ?1
+AND (?2 IS NULL OR posts.feed_id = ?2)
+ORDER BY posts.published_at DESC NULLS FIRST, posts.id
+LIMIT ?3`
+
+func (s *SQLite) GetPostListForUser(ctx context.Context, arg database.GetPostListForUserParams) ([]database.GetPostListForUserRow, error) {
+	rows, err := s.db.QueryContext(ctx, getPostListForUser, arg.UserID, arg.FeedID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPostListForUserRow
+	for rows.Next() {
+		var i database.GetPostListForUserRow
+		post, err := scanPost(rows, &i.FeedName, &i.IsRead, &i.IsSaved)
+		if err != nil {
+			return nil, err
+		}
+		i.ID = post.ID
+		i.CreatedAt = post.CreatedAt
+		i.UpdatedAt = post.UpdatedAt
+		i.Title = post.Title
+		i.Url = post.Url
+		i.Description = post.Description
+		i.PublishedAt = post.PublishedAt
+		i.FeedID = post.FeedID
+		i.Content = post.Content
+		i.Author = post.Author
+		i.Categories = post.Categories
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsByFeedID = `SELECT ` + postColumns + `
+FROM posts
+WHERE feed_id = ?`
+
+func (s *SQLite) GetPostsByFeedID(ctx context.Context, feedID uuid.UUID) ([]database.Post, error) {
+	return s.queryPosts(ctx, getPostsByFeedID, feedID)
+}
+
+const getPostsByIDPrefix = `SELECT ` + postColumns + `, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id LIKE ?
+ORDER BY posts.id
+LIMIT 2`
+
+func (s *SQLite) GetPostsByIDPrefix(ctx context.Context, idPrefix string) ([]database.GetPostsByIDPrefixRow, error) {
+	rows, err := s.db.QueryContext(ctx, getPostsByIDPrefix, idPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetPostsByIDPrefixRow
+	for rows.Next() {
+		var i database.GetPostsByIDPrefixRow
+		post, err := scanPost(rows, &i.FeedName, &i.FeedUrl)
+		if err != nil {
+			return nil, err
+		}
+		i.ID = post.ID
+		i.CreatedAt = post.CreatedAt
+		i.UpdatedAt = post.UpdatedAt
+		i.Title = post.Title
+		i.Url = post.Url
+		i.Description = post.Description
+		i.PublishedAt = post.PublishedAt
+		i.FeedID = post.FeedID
+		i.Content = post.Content
+		i.Author = post.Author
+		i.Categories = post.Categories
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostsForUser = `SELECT ` + postColumns + `
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = ?
+ORDER BY posts.published_at DESC NULLS FIRST, posts.id
+LIMIT ? OFFSET ?`
+
+func (s *SQLite) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.Post, error) {
+	return s.queryPosts(ctx, getPostsForUser, arg.UserID, arg.Limit, arg.Offset)
+}
+
+const getUnreadCountsForUser = `SELECT posts.feed_id, COUNT(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = ?1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = ?1
+)
+GROUP BY posts.feed_id`
+
+func (s *SQLite) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	rows, err := s.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i database.GetUnreadCountsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Unread); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markPostRead = `INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING`
+
+func (s *SQLite) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	_, err := s.db.ExecContext(ctx, markPostRead, arg.UserID, arg.PostID, utc(arg.ReadAt))
+	return err
+}
+
+const markPostUnread = `DELETE FROM post_reads
+WHERE user_id = ? AND post_id = ?`
+
+func (s *SQLite) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) (int64, error) {
+	return s.exec(ctx, markPostUnread, arg.UserID, arg.PostID)
+}
+
+const moveFeedPosts = `UPDATE posts
+SET feed_id = ?, updated_at = ?
+WHERE feed_id = ?`
+
+func (s *SQLite) MoveFeedPosts(ctx context.Context, arg database.MoveFeedPostsParams) error {
+	_, err := s.db.ExecContext(ctx, moveFeedPosts, arg.ToFeedID, utc(time.Now()), arg.FromFeedID)
+	return err
+}
+
+const setPostContent = `UPDATE posts
+SET content = ?, updated_at = ?
+WHERE id = ?`
+
+func (s *SQLite) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	_, err := s.db.ExecContext(ctx, setPostContent, arg.Content, utc(time.Now()), arg.ID)
+	return err
+}
+
+const createPostEnclosure = `INSERT INTO post_enclosures (id, post_id, url, mime_type, length)
+VALUES (?, ?, ?, ?, ?)`
+
+func (s *SQLite) CreatePostEnclosure(ctx context.Context, arg database.CreatePostEnclosureParams) error {
+	_, err := s.db.ExecContext(ctx, createPostEnclosure,
+		arg.ID,
+		arg.PostID,
+		arg.Url,
+		arg.MimeType,
+		arg.Length,
+	)
+	return err
+}
+
+const getPostEnclosures = `SELECT id, post_id, url, mime_type, length
+FROM post_enclosures
+WHERE post_id = ?`
+
+func (s *SQLite) GetPostEnclosures(ctx context.Context, postID uuid.UUID) ([]database.PostEnclosure, error) {
+	rows, err := s.db.QueryContext(ctx, getPostEnclosures, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.PostEnclosure
+	for rows.Next() {
+		var i database.PostEnclosure
+		if err := rows.Scan(&i.ID, &i.PostID, &i.Url, &i.MimeType, &i.Length); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const savePost = `INSERT INTO saved_posts (user_id, post_id, created_at)
+VALUES (?, ?, ?)
+ON CONFLICT (user_id, post_id) DO NOTHING`
+
+func (s *SQLite) SavePost(ctx context.Context, arg database.SavePostParams) error {
+	_, err := s.db.ExecContext(ctx, savePost, arg.UserID, arg.PostID, utc(arg.CreatedAt))
+	return err
+}
+
+const unsavePost = `DELETE FROM saved_posts
+WHERE user_id = ? AND post_id = ?`
+
+func (s *SQLite) UnsavePost(ctx context.Context, arg database.UnsavePostParams) (int64, error) {
+	return s.exec(ctx, unsavePost, arg.UserID, arg.PostID)
+}
+
+const createUserFilter = `INSERT INTO user_filters (id, created_at, updated_at, user_id, action, field, pattern, is_regex, feed_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func (s *SQLite) CreateUserFilter(ctx context.Context, arg database.CreateUserFilterParams) (database.UserFilter, error) {
+	_, err := s.db.ExecContext(ctx, createUserFilter,
+		arg.ID,
+		utc(arg.CreatedAt),
+		utc(arg.UpdatedAt),
+		arg.UserID,
+		arg.Action,
+		arg.Field,
+		arg.Pattern,
+		arg.IsRegex,
+		arg.FeedID,
+	)
+	if err != nil {
+		return database.UserFilter{}, err
+	}
+	return database.UserFilter{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		Action:    arg.Action,
+		Field:     arg.Field,
+		Pattern:   arg.Pattern,
+		IsRegex:   arg.IsRegex,
+		FeedID:    arg.FeedID,
+	}, nil
+}
+
+const deleteUserFilter = `DELETE FROM user_filters
+WHERE id = ? AND user_id = ?`
+
+func (s *SQLite) DeleteUserFilter(ctx context.Context, arg database.DeleteUserFilterParams) (int64, error) {
+	return s.exec(ctx, deleteUserFilter, arg.ID, arg.UserID)
+}
+
+const getUserFiltersForUser = `SELECT user_filters.id, user_filters.created_at, user_filters.updated_at, user_filters.user_id, user_filters.action, user_filters.field, user_filters.pattern, user_filters.is_regex, user_filters.feed_id, feeds.url AS feed_url
+FROM user_filters
+LEFT JOIN feeds ON feeds.id = user_filters.feed_id
+WHERE user_filters.user_id = ?
+ORDER BY user_filters.created_at`
+
+func (s *SQLite) GetUserFiltersForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUserFiltersForUserRow, error) {
+	rows, err := s.db.QueryContext(ctx, getUserFiltersForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []database.GetUserFiltersForUserRow
+	for rows.Next() {
+		var i database.GetUserFiltersForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Action,
+			&i.Field,
+			&i.Pattern,
+			&i.IsRegex,
+			&i.FeedID,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFilters = `UPDATE user_filters
+SET feed_id = ?, updated_at = ?
+WHERE feed_id = ?`
+
+func (s *SQLite) MoveFeedFilters(ctx context.Context, arg database.MoveFeedFiltersParams) error {
+	_, err := s.db.ExecContext(ctx, moveFeedFilters, arg.ToFeedID, utc(time.Now()), arg.FromFeedID)
+	return err
+}
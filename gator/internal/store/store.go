@@ -0,0 +1,96 @@
+// Package store defines the storage interface the gator commands run against,
+// so the same handlers work with PostgreSQL and with an in-memory store in
+// tests. Methods take and return the types generated by sqlc in the database
+// package and behave like the queries of the same name.
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+// ErrConflict is returned when a row would violate a uniqueness constraint,
+// such as a second post with the same URL
+var ErrConflict = errors.New("duplicate key")
+
+// Store is the storage used by the commands. Lookups of a single row return
+// sql.ErrNoRows when nothing matches.
+type Store interface {
+	// Users
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	DeleteAllUsers(ctx context.Context) error
+	GetUserByName(ctx context.Context, name string) (database.User, error)
+	GetUsers(ctx context.Context) ([]database.User, error)
+	SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error
+
+	// Sessions
+	CreateSession(ctx context.Context, arg database.CreateSessionParams) error
+	DeleteExpiredSessions(ctx context.Context) error
+	DeleteSession(ctx context.Context, tokenHash string) error
+	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
+	GetSession(ctx context.Context, tokenHash string) (database.Session, error)
+
+	// Feeds
+	CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.CreateFeedRow, error)
+	DeferFeed(ctx context.Context, arg database.DeferFeedParams) error
+	DeleteFeed(ctx context.Context, id uuid.UUID) error
+	GetFeedByUrl(ctx context.Context, url string) (database.GetFeedByUrlRow, error)
+	GetFeedInfoByUrl(ctx context.Context, url string) (database.GetFeedInfoByUrlRow, error)
+	GetFeedsWithUserNames(ctx context.Context) ([]database.GetFeedsWithUserNamesRow, error)
+	GetNextFeedsToFetch(ctx context.Context, limit int32) ([]database.Feed, error)
+	MarkFeedDead(ctx context.Context, id uuid.UUID) error
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) error
+	ReviveFeed(ctx context.Context, id uuid.UUID) error
+	SetFeedFetchFullContent(ctx context.Context, arg database.SetFeedFetchFullContentParams) error
+	SetFeedParseWarning(ctx context.Context, arg database.SetFeedParseWarningParams) error
+	SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error
+	UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error
+	UpdateFeedUrl(ctx context.Context, arg database.UpdateFeedUrlParams) error
+
+	// Feed aliases
+	CreateFeedAlias(ctx context.Context, arg database.CreateFeedAliasParams) error
+	DeleteFeedAlias(ctx context.Context, url string) error
+	MoveFeedAliases(ctx context.Context, arg database.MoveFeedAliasesParams) error
+
+	// Feed follows
+	CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error)
+	DeleteFeedFollowByUserAndUrl(ctx context.Context, arg database.DeleteFeedFollowByUserAndUrlParams) error
+	GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error)
+	MoveFeedFollows(ctx context.Context, arg database.MoveFeedFollowsParams) error
+
+	// Posts
+	CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error)
+	DeleteExcessPosts(ctx context.Context, defaultMaxPosts int32) (int64, error)
+	DeleteExpiredPosts(ctx context.Context, defaultMaxAgeDays int32) (int64, error)
+	GetPostByUrl(ctx context.Context, url string) (database.Post, error)
+	GetPostListForUser(ctx context.Context, arg database.GetPostListForUserParams) ([]database.GetPostListForUserRow, error)
+	GetPostsByFeedID(ctx context.Context, feedID uuid.UUID) ([]database.Post, error)
+	GetPostsByIDPrefix(ctx context.Context, idPrefix string) ([]database.GetPostsByIDPrefixRow, error)
+	GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.Post, error)
+	GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error)
+	MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) (int64, error)
+	MoveFeedPosts(ctx context.Context, arg database.MoveFeedPostsParams) error
+	SetPostContent(ctx context.Context, arg database.SetPostContentParams) error
+
+	// Post enclosures
+	CreatePostEnclosure(ctx context.Context, arg database.CreatePostEnclosureParams) error
+	GetPostEnclosures(ctx context.Context, postID uuid.UUID) ([]database.PostEnclosure, error)
+
+	// Saved posts
+	SavePost(ctx context.Context, arg database.SavePostParams) error
+	UnsavePost(ctx context.Context, arg database.UnsavePostParams) (int64, error)
+
+	// Filters
+	CreateUserFilter(ctx context.Context, arg database.CreateUserFilterParams) (database.UserFilter, error)
+	DeleteUserFilter(ctx context.Context, arg database.DeleteUserFilterParams) (int64, error)
+	GetUserFiltersForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUserFiltersForUserRow, error)
+	MoveFeedFilters(ctx context.Context, arg database.MoveFeedFiltersParams) error
+
+	// InTx runs fn against a store whose changes are committed together when
+	// fn returns nil and discarded when it returns an error
+	InTx(ctx context.Context, fn func(Store) error) error
+}
@@ -0,0 +1,344 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/database"
+	"github.com/sushiqiren/gator/internal/migrate"
+)
+
+// testStores lists the stores every test below runs against
+var testStores = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store { return NewMemory() }},
+	{"sqlite", openTestSQLite},
+}
+
+// openTestSQLite returns a SQLite store in a temporary file with the schema
+// gator ships for SQLite
+func openTestSQLite(t *testing.T) Store {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "g.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := migrate.Load(os.DirFS("../../sql/sqlite/schema"))
+	if err != nil {
+		t.Fatalf("error loading migrations: %v", err)
+	}
+	if _, err := migrate.Up(context.Background(), db, migrate.SQLite, migrations); err != nil {
+		t.Fatalf("error migrating: %v", err)
+	}
+	return NewSQLite(db)
+}
+
+// forEachStore runs test as a subtest for every store in testStores
+func forEachStore(t *testing.T, test func(t *testing.T, open func(t *testing.T) Store)) {
+	for _, st := range testStores {
+		t.Run(st.name, func(t *testing.T) { test(t, st.open) })
+	}
+}
+
+func newUser(name string) database.CreateUserParams {
+	now := time.Now()
+	return database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name}
+}
+
+func userNames(t *testing.T, s Store) []string {
+	t.Helper()
+	users, err := s.GetUsers(context.Background())
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	var names []string
+	for _, user := range users {
+		names = append(names, user.Name)
+	}
+	return names
+}
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name  string
+		fnErr error
+		want  []string
+	}{
+		{"commit", nil, []string{"alice", "bob"}},
+		{"rollback", errRollback, []string{"alice"}},
+	}
+	forEachStore(t, func(t *testing.T, open func(t *testing.T) Store) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := open(t)
+				if _, err := s.CreateUser(ctx, newUser("alice")); err != nil {
+					t.Fatalf("CreateUser: %v", err)
+				}
+				err := s.InTx(ctx, func(q Store) error {
+					if _, err := q.CreateUser(ctx, newUser("bob")); err != nil {
+						return err
+					}
+					return tt.fnErr
+				})
+				if !errors.Is(err, tt.fnErr) {
+					t.Fatalf("InTx returned %v, want %v", err, tt.fnErr)
+				}
+				if got := userNames(t, s); !slices.Equal(got, tt.want) {
+					t.Errorf("users = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+// fixture creates rows in a store for the tests below
+type fixture struct {
+	t    *testing.T
+	ctx  context.Context
+	s    Store
+	user database.User
+}
+
+func newFixture(t *testing.T, open func(t *testing.T) Store) *fixture {
+	f := &fixture{t: t, ctx: context.Background(), s: open(t)}
+	user, err := f.s.CreateUser(f.ctx, newUser("alice"))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	f.user = user
+	return f
+}
+
+func (f *fixture) feed(url string, updatedAt time.Time) uuid.UUID {
+	f.t.Helper()
+	feed, err := f.s.CreateFeed(f.ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: updatedAt,
+		UpdatedAt: updatedAt,
+		Name:      url,
+		Url:       url,
+		UserID:    f.user.ID,
+	})
+	if err != nil {
+		f.t.Fatalf("CreateFeed: %v", err)
+	}
+	return feed.ID
+}
+
+// post creates a post; a zero publishedAt stores NULL
+func (f *fixture) post(feedID uuid.UUID, url string, publishedAt time.Time) uuid.UUID {
+	f.t.Helper()
+	post, err := f.s.CreatePost(f.ctx, database.CreatePostParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		Title:       url,
+		Url:         url,
+		PublishedAt: sql.NullTime{Time: publishedAt, Valid: !publishedAt.IsZero()},
+		FeedID:      feedID,
+		Categories:  []string{},
+	})
+	if err != nil {
+		f.t.Fatalf("CreatePost: %v", err)
+	}
+	return post.ID
+}
+
+func (f *fixture) save(postID uuid.UUID) {
+	f.t.Helper()
+	err := f.s.SavePost(f.ctx, database.SavePostParams{UserID: f.user.ID, PostID: postID, CreatedAt: time.Now()})
+	if err != nil {
+		f.t.Fatalf("SavePost: %v", err)
+	}
+}
+
+func (f *fixture) postURLs(feedID uuid.UUID) []string {
+	f.t.Helper()
+	posts, err := f.s.GetPostsByFeedID(f.ctx, feedID)
+	if err != nil {
+		f.t.Fatalf("GetPostsByFeedID: %v", err)
+	}
+	var urls []string
+	for _, post := range posts {
+		urls = append(urls, post.Url)
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+func TestCreatePostConflict(t *testing.T) {
+	forEachStore(t, func(t *testing.T, open func(t *testing.T) Store) {
+		f := newFixture(t, open)
+		feedID := f.feed("http://x/feed", time.Now())
+		f.post(feedID, "http://x/1", time.Now())
+
+		_, err := f.s.CreatePost(f.ctx, database.CreatePostParams{
+			ID:         uuid.New(),
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+			Title:      "again",
+			Url:        "http://x/1",
+			FeedID:     feedID,
+			Categories: []string{},
+		})
+		if !errors.Is(err, ErrConflict) {
+			t.Errorf("CreatePost with a duplicate URL returned %v, want ErrConflict", err)
+		}
+	})
+}
+
+func TestGetNextFeedsToFetch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, open func(t *testing.T) Store) {
+		f := newFixture(t, open)
+		base := time.Now().Add(-time.Hour)
+		fetchedLast := f.feed("fetched-last", base)
+		fetchedFirst := f.feed("fetched-first", base.Add(time.Minute))
+		f.feed("never-fetched-new", base.Add(3*time.Minute))
+		f.feed("never-fetched-old", base.Add(2*time.Minute))
+		dead := f.feed("dead", base)
+		later := f.feed("scheduled-later", base)
+
+		for _, id := range []uuid.UUID{fetchedFirst, fetchedLast} {
+			if err := f.s.MarkFeedFetched(f.ctx, id); err != nil {
+				t.Fatalf("MarkFeedFetched: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if err := f.s.MarkFeedDead(f.ctx, dead); err != nil {
+			t.Fatalf("MarkFeedDead: %v", err)
+		}
+		err := f.s.DeferFeed(f.ctx, database.DeferFeedParams{ID: later, DelaySeconds: time.Hour.Seconds()})
+		if err != nil {
+			t.Fatalf("DeferFeed: %v", err)
+		}
+
+		tests := []struct {
+			limit int32
+			want  []string
+		}{
+			{10, []string{"never-fetched-old", "never-fetched-new", "fetched-first", "fetched-last"}},
+			{2, []string{"never-fetched-old", "never-fetched-new"}},
+		}
+		for _, tt := range tests {
+			feeds, err := f.s.GetNextFeedsToFetch(f.ctx, tt.limit)
+			if err != nil {
+				t.Fatalf("GetNextFeedsToFetch: %v", err)
+			}
+			var got []string
+			for _, feed := range feeds {
+				got = append(got, feed.Url)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetNextFeedsToFetch(%d) = %v, want %v", tt.limit, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestRetention(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		// prune deletes posts from the feed and reports how many
+		prune   func(s Store) (int64, error)
+		wantN   int64
+		wantURL []string
+	}{
+		{
+			name:  "excess posts drop undated posts first and keep saved ones",
+			prune: func(s Store) (int64, error) { return s.DeleteExcessPosts(context.Background(), 2) },
+			wantN: 2,
+			// new and mid are the newest unsaved posts, old-saved is kept on top
+			wantURL: []string{"mid", "new", "old-saved"},
+		},
+		{
+			name:    "expired posts keep saved ones",
+			prune:   func(s Store) (int64, error) { return s.DeleteExpiredPosts(context.Background(), 5) },
+			wantN:   1,
+			wantURL: []string{"mid", "new", "old-saved", "undated"},
+		},
+		{
+			name:    "zero limits disable pruning",
+			prune:   func(s Store) (int64, error) { return s.DeleteExcessPosts(context.Background(), 0) },
+			wantN:   0,
+			wantURL: []string{"mid", "new", "old", "old-saved", "undated"},
+		},
+	}
+
+	forEachStore(t, func(t *testing.T, open func(t *testing.T) Store) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				f := newFixture(t, open)
+				feedID := f.feed("http://x/feed", now)
+				f.post(feedID, "new", now.AddDate(0, 0, -1))
+				f.post(feedID, "mid", now.AddDate(0, 0, -2))
+				f.post(feedID, "old", now.AddDate(0, 0, -10))
+				f.save(f.post(feedID, "old-saved", now.AddDate(0, 0, -20)))
+				f.post(feedID, "undated", time.Time{})
+
+				n, err := tt.prune(f.s)
+				if err != nil {
+					t.Fatalf("prune: %v", err)
+				}
+				if n != tt.wantN {
+					t.Errorf("deleted %d posts, want %d", n, tt.wantN)
+				}
+				if got := f.postURLs(feedID); !slices.Equal(got, tt.wantURL) {
+					t.Errorf("remaining posts = %v, want %v", got, tt.wantURL)
+				}
+			})
+		}
+	})
+}
+
+func TestGetPostsForUserOrder(t *testing.T) {
+	forEachStore(t, func(t *testing.T, open func(t *testing.T) Store) {
+		f := newFixture(t, open)
+		now := time.Now()
+		feedID := f.feed("http://x/feed", now)
+		_, err := f.s.CreateFeedFollow(f.ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    f.user.ID,
+			FeedID:    feedID,
+		})
+		if err != nil {
+			t.Fatalf("CreateFeedFollow: %v", err)
+		}
+		// SQLite compares timestamps as text, so the zones must not change the
+		// order: old has the later wall clock time
+		east := time.FixedZone("UTC+14", 14*60*60)
+		west := time.FixedZone("UTC-10", -10*60*60)
+		f.post(feedID, "old", now.Add(-2*time.Hour).In(east))
+		f.post(feedID, "undated", time.Time{})
+		f.post(feedID, "new", now.Add(-time.Hour).In(west))
+
+		posts, err := f.s.GetPostsForUser(f.ctx, database.GetPostsForUserParams{UserID: f.user.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("GetPostsForUser: %v", err)
+		}
+		var got []string
+		for _, post := range posts {
+			got = append(got, post.Url)
+		}
+		// PostgreSQL sorts NULLs first in descending order
+		if want := []string{"undated", "new", "old"}; !slices.Equal(got, want) {
+			t.Errorf("posts = %v, want %v", got, want)
+		}
+	})
+}
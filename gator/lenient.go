@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+// bareAmpersand matches an & that does not start a character or entity reference
+var bareAmpersand = regexp.MustCompile(`&([^#A-Za-z]|#[^0-9xX]|#[xX][^0-9A-Fa-f]|[A-Za-z][A-Za-z0-9]*[^A-Za-z0-9;]|$)`)
+
+// sanitizeXML repairs the most common problems found in real-world feeds:
+// a leading BOM, characters that are illegal in XML and unescaped ampersands
+func sanitizeXML(body []byte) []byte {
+	body = bytes.TrimPrefix(body, utf8BOM)
+
+	clean := make([]byte, 0, len(body))
+	for len(body) > 0 {
+		r, size := utf8.DecodeRune(body)
+		if isXMLChar(r) && !(r == utf8.RuneError && size == 1) {
+			clean = append(clean, body[:size]...)
+		}
+		body = body[size:]
+	}
+
+	// Repeat until stable because matches of consecutive ampersands overlap
+	for {
+		fixed := bareAmpersand.ReplaceAll(clean, []byte("&amp;$1"))
+		if bytes.Equal(fixed, clean) {
+			return fixed
+		}
+		clean = fixed
+	}
+}
+
+// isXMLChar reports whether a rune is allowed in an XML 1.0 document
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// feedAutoClose lists the HTML void elements, such as <br>, that the lenient
+// decoder closes when a feed embeds them unescaped. <link> is left out because
+// it is a regular element in RSS that holds the item's URL.
+var feedAutoClose = slices.DeleteFunc(slices.Clone(xml.HTMLAutoClose), func(name string) bool {
+	return name == "link"
+})
+
+// newLenientFeedDecoder returns a non-strict decoder that understands HTML entities
+// such as &nbsp; and closes unterminated HTML elements
+func newLenientFeedDecoder(body []byte) *xml.Decoder {
+	decoder := newFeedDecoder(body)
+	decoder.Strict = false
+	decoder.AutoClose = feedAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+// parseFeed decodes a feed body. Documents that are not well-formed are
+// sanitized and decoded leniently; if that still fails, every item that can be
+// read before the error is salvaged. The returned warning describes any recovery
+// that was needed and is empty for well-formed feeds.
+func parseFeed(body []byte) (*RSSFeed, string, error) {
+	var feed RSSFeed
+	strictErr := newFeedDecoder(body).Decode(&feed)
+	if strictErr == nil {
+		return &feed, "", nil
+	}
+
+	clean := sanitizeXML(body)
+	feed = RSSFeed{}
+	if err := newLenientFeedDecoder(clean).Decode(&feed); err == nil {
+		return &feed, fmt.Sprintf("recovered from malformed XML: %v", strictErr), nil
+	}
+
+	salvaged, err := salvageFeed(clean)
+	if len(salvaged.Channel.Items) == 0 && salvaged.Channel.Title == "" {
+		return nil, "", fmt.Errorf("error parsing feed: %v", strictErr)
+	}
+	if err == nil {
+		// Salvaging read to the end, so the strict error describes the problem
+		err = strictErr
+	}
+	return salvaged, fmt.Sprintf("salvaged %d items from malformed XML: %v", len(salvaged.Channel.Items), err), nil
+}
+
+// salvageFeed walks the token stream and decodes the channel fields and items
+// one by one, keeping everything read before the first unrecoverable error
+func salvageFeed(body []byte) (*RSSFeed, error) {
+	var feed RSSFeed
+	decoder := newLenientFeedDecoder(body)
+	inChannel := false
+
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return &feed, nil
+		}
+		if err != nil {
+			return &feed, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "channel":
+				inChannel = true
+			case inChannel && t.Name.Local == "item":
+				var item RSSItem
+				if err := decoder.DecodeElement(&item, &t); err != nil {
+					return &feed, err
+				}
+				feed.Channel.Items = append(feed.Channel.Items, item)
+			case inChannel && t.Name.Local == "title" && feed.Channel.Title == "":
+				if err := decoder.DecodeElement(&feed.Channel.Title, &t); err != nil {
+					return &feed, err
+				}
+			case inChannel && t.Name.Local == "description" && feed.Channel.Description == "":
+				if err := decoder.DecodeElement(&feed.Channel.Description, &t); err != nil {
+					return &feed, err
+				}
+			case inChannel && t.Name.Local == "link" && t.Name.Space == "":
+				var link string
+				if err := decoder.DecodeElement(&link, &t); err != nil {
+					return &feed, err
+				}
+				feed.Channel.Links = append(feed.Channel.Links, link)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "channel" {
+				inChannel = false
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseFeedLenientKeepsLinks(t *testing.T) {
+	body := `<rss><channel><title>T</title><link>http://x/</link>
+		<item><title>A &nbsp; B</title><link>http://x/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>
+	</channel></rss>`
+
+	feed, warning, err := parseFeed([]byte(body))
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if warning == "" {
+		t.Errorf("expected a warning for the lenient path")
+	}
+	if got := feed.Channel.Link(); got != "http://x/" {
+		t.Errorf("channel link = %q, want %q", got, "http://x/")
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Link != "http://x/1" {
+		t.Errorf("item link = %q, want %q", item.Link, "http://x/1")
+	}
+	if item.PubDate != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("item pubDate = %q", item.PubDate)
+	}
+}
+
+func TestParseFeedSalvageWarning(t *testing.T) {
+	// The channel is not inside <rss>, so only salvaging finds the items and
+	// it reads to the end of the document without an error of its own
+	body := `<wrapper><rss><channel><title>T</title>
+		<item><title>A</title><link>http://x/1</link></item>
+	</channel></rss></wrapper>`
+
+	feed, warning, err := parseFeed([]byte(body))
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+	}
+	if !strings.HasPrefix(warning, "salvaged 1 items from malformed XML: ") || strings.Contains(warning, "<nil>") {
+		t.Errorf("unexpected warning %q", warning)
+	}
+}
+
+func TestSanitizeXML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bom", "\xef\xbb\xbf<a/>", "<a/>"},
+		{"bare ampersand", "<a>Q&A</a>", "<a>Q&amp;A</a>"},
+		{"consecutive ampersands", "<a>&&</a>", "<a>&amp;&amp;</a>"},
+		{"references kept", "<a>&amp; &#38; &#x26; &nbsp;</a>", "<a>&amp; &#38; &#x26; &nbsp;</a>"},
+		{"control characters", "<a>x\x00\x08\x1fy</a>", "<a>xy</a>"},
+		{"invalid utf-8", "<a>x\xffy</a>", "<a>xy</a>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(sanitizeXML([]byte(tt.in))); got != tt.want {
+				t.Errorf("sanitizeXML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedMalformed(t *testing.T) {
+	const item = `<item><title>%s</title><link>http://x/1</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>`
+	tests := []struct {
+		name      string
+		body      string
+		wantTitle string
+		// wantWarning is a prefix of the expected warning, empty for none
+		wantWarning string
+	}{
+		{
+			name:      "well-formed",
+			body:      `<rss><channel><title>T</title>` + fmt.Sprintf(item, "A") + `</channel></rss>`,
+			wantTitle: "A",
+		},
+		{
+			name:      "bom",
+			body:      "\xef\xbb\xbf<rss><channel><title>T</title>" + fmt.Sprintf(item, "A") + `</channel></rss>`,
+			wantTitle: "A",
+		},
+		{
+			name:        "bom and bare ampersand",
+			body:        "\xef\xbb\xbf<rss><channel><title>T</title>" + fmt.Sprintf(item, "Q&A") + `</channel></rss>`,
+			wantTitle:   "Q&A",
+			wantWarning: "recovered from malformed XML",
+		},
+		{
+			name:        "bare ampersand",
+			body:        `<rss><channel><title>T</title>` + fmt.Sprintf(item, "Q&A") + `</channel></rss>`,
+			wantTitle:   "Q&A",
+			wantWarning: "recovered from malformed XML",
+		},
+		{
+			name:        "html entity",
+			body:        `<rss><channel><title>T</title>` + fmt.Sprintf(item, "A&nbsp;B") + `</channel></rss>`,
+			wantTitle:   "A B",
+			wantWarning: "recovered from malformed XML",
+		},
+		{
+			name:        "control characters",
+			body:        `<rss><channel><title>T</title>` + fmt.Sprintf(item, "A\x0bB") + `</channel></rss>`,
+			wantTitle:   "AB",
+			wantWarning: "recovered from malformed XML",
+		},
+		{
+			name:        "truncated",
+			body:        `<rss><channel><title>T</title>` + fmt.Sprintf(item, "A") + `<item><title>B</ti`,
+			wantTitle:   "A",
+			wantWarning: "salvaged 1 items from malformed XML",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, warning, err := parseFeed([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseFeed: %v", err)
+			}
+			if tt.wantWarning == "" && warning != "" || !strings.HasPrefix(warning, tt.wantWarning) {
+				t.Errorf("warning = %q, want prefix %q", warning, tt.wantWarning)
+			}
+			if feed.Channel.Title != "T" {
+				t.Errorf("channel title = %q, want %q", feed.Channel.Title, "T")
+			}
+			if len(feed.Channel.Items) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Items))
+			}
+			item := feed.Channel.Items[0]
+			if item.Title != tt.wantTitle {
+				t.Errorf("item title = %q, want %q", item.Title, tt.wantTitle)
+			}
+			if item.Link != "http://x/1" || item.PubDate == "" {
+				t.Errorf("item link = %q, pubDate = %q", item.Link, item.PubDate)
+			}
+		})
+	}
+}
+
+func TestParseFeedUnreadable(t *testing.T) {
+	if _, _, err := parseFeed([]byte("not a feed")); err == nil {
+		t.Errorf("expected an error for a body without a channel")
+	}
+}
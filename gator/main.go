@@ -3,65 +3,63 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
-	"html"
-	"io"
 	"log"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sushiqiren/gator/internal/config"
+	"github.com/sushiqiren/gator/internal/content"
 	"github.com/sushiqiren/gator/internal/database"
+	"github.com/sushiqiren/gator/internal/migrate"
+	"github.com/sushiqiren/gator/internal/store"
 
-	"github.com/lib/pq"
+	_ "github.com/lib/pq"
+	"golang.org/x/term"
 	
 )
 
-type state struct {
-	db  *database.Queries
-	cfg *config.Config
-}
-
-type command struct {
-	name string
-	args []string
-}
-
-type commands struct {
-	handlers map[string]func(*state, command) error
-}
-
-func (c *commands) register(name string, f func(*state, command) error) {
-	c.handlers[name] = f
-}
+// version is reported in the User-Agent header and can be set at build time
+// with -ldflags "-X main.version=v1.2.3"
+var version = "dev"
 
-func (c *commands) run(s *state, cmd command) error {
-	if handler, exists := c.handlers[cmd.name]; exists {
-		return handler(s, cmd)
-	}
-	return fmt.Errorf("unknown command: %s", cmd.name)
+type state struct {
+	db store.Store
+	// conn is the connection behind db, used for schema migrations
+	conn    *sql.DB
+	dialect *migrate.Dialect
+	cfg     *config.Config
+	fetcher *fetcher
+	// out receives the records printed by listing commands
+	out *output
 }
 
 func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("login command expects a username argument")
-	}
 	username := cmd.args[0]
 
 	// Check if the user exists
-	_, err := s.db.GetUserByName(context.Background(), username)
+	user, err := s.db.GetUserByName(context.Background(), username)
 	if err == sql.ErrNoRows {
 		return fmt.Errorf("user with name %s does not exist", username)
 	} else if err != nil {
 		return fmt.Errorf("error checking for existing user: %v", err)
 	}
 
-	// Set the current user in the config
-	err = s.cfg.SetUser(username)
+	if user.PasswordHash.Valid {
+		if err := checkPassword(user, "Password for "+username); err != nil {
+			return err
+		}
+	}
+
+	// Start a session and set the current user in the config
+	err = startSession(context.Background(), s, user)
 	if err != nil {
 		return err
 	}
@@ -70,9 +68,6 @@ func handlerLogin(s *state, cmd command) error {
 }
 
 func handlerRegister(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("register command expects a username argument")
-	}
 	username := cmd.args[0]
 
 	// Check if the user already exists
@@ -83,12 +78,25 @@ func handlerRegister(s *state, cmd command) error {
 		return fmt.Errorf("error checking for existing user: %v", err)
 	}
 
+	var password string
+	if !cmd.boolFlag("no-password") {
+		password, err = readNewPassword("Password for " + username)
+		if err != nil {
+			return err
+		}
+	}
+	passwordHash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
 	// Create a new user
 	newUser := database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      username,
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         username,
+		PasswordHash: passwordHash,
 	}
 
 	createdUser, err := s.db.CreateUser(context.Background(), newUser)
@@ -96,18 +104,20 @@ func handlerRegister(s *state, cmd command) error {
 		return fmt.Errorf("error creating new user: %v", err)
 	}
 
-	// Set the current user in the config
-	err = s.cfg.SetUser(username)
+	// Start a session and set the current user in the config
+	err = startSession(context.Background(), s, createdUser)
 	if err != nil {
 		return fmt.Errorf("error setting current user: %v", err)
 	}
 
 	fmt.Printf("User %s has been created\n", username)
-	log.Printf("User created: %+v\n", createdUser)
+	log.Printf("User created: %s (%s)\n", createdUser.Name, createdUser.ID)
 	return nil
 }
 
-func handlerReset(s *state, cmd command) error {
+// handlerReset deletes every user. It runs as the current user, so only
+// someone logged in with a valid session can wipe the password-protected users.
+func handlerReset(s *state, cmd command, user database.User) error {
 	err := s.db.DeleteAllUsers(context.Background())
 	if err != nil {
 		return fmt.Errorf("error deleting all users: %v", err)
@@ -116,6 +126,11 @@ func handlerReset(s *state, cmd command) error {
 	return nil
 }
 
+type userRecord struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+}
+
 func handlerUsers(s *state, cmd command) error {
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
@@ -124,19 +139,22 @@ func handlerUsers(s *state, cmd command) error {
 
 	currentUser := s.cfg.CurrentUserName
 	for _, user := range users {
-		if user.Name == currentUser {
-			fmt.Printf("* %s (current)\n", user.Name)
-		} else {
-			fmt.Printf("* %s\n", user.Name)
+		record := userRecord{Name: user.Name, Current: user.Name == currentUser}
+		err := s.out.emit(record, func() {
+			if record.Current {
+				fmt.Printf("* %s (current)\n", user.Name)
+			} else {
+				fmt.Printf("* %s\n", user.Name)
+			}
+		})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("agg command expects a time_between_reqs argument")
-	}
 	timeBetweenReqsStr := cmd.args[0]
 
 	// Parse the time_between_reqs argument
@@ -145,6 +163,15 @@ func handlerAgg(s *state, cmd command) error {
 		return fmt.Errorf("error parsing time_between_reqs: %v", err)
 	}
 
+	// Parse the optional number of feeds to fetch concurrently on each tick
+	concurrency := 1
+	if len(cmd.args) > 1 {
+		concurrency, err = strconv.Atoi(cmd.args[1])
+		if err != nil || concurrency < 1 {
+			return fmt.Errorf("error parsing concurrency: expected a positive number, got %q", cmd.args[1])
+		}
+	}
+
 	fmt.Printf("Collecting feeds every %s\n", timeBetweenReqs)
 
 	// Use a time.Ticker to run scrapeFeeds periodically
@@ -153,54 +180,25 @@ func handlerAgg(s *state, cmd command) error {
 
 	// Run scrapeFeeds immediately and then every time the ticker ticks
 	for {
-		scrapeFeeds(s)
-		<-ticker.C
-	}
-}
-
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("User-Agent", "gator")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch feed: %s", resp.Status)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+		if err := scrapeFeeds(s, concurrency); err != nil {
+			log.Printf("%v", err)
+		}
 
-	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, err
-	}
+		// Optionally apply the retention policy after each round
+		if s.cfg.Retention.PruneOnAgg {
+			expired, excess, err := prunePosts(context.Background(), s)
+			if err != nil {
+				log.Printf("error pruning posts: %v", err)
+			} else if expired+excess > 0 {
+				log.Printf("pruned %d posts (%d expired, %d over feed limit)", expired+excess, expired, excess)
+			}
+		}
 
-	// Unescape HTML entities in the feed
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
-	for i := range feed.Channel.Items {
-		feed.Channel.Items[i].Title = html.UnescapeString(feed.Channel.Items[i].Title)
-		feed.Channel.Items[i].Description = html.UnescapeString(feed.Channel.Items[i].Description)
+		<-ticker.C
 	}
-
-	return &feed, nil
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 2 {
-		return fmt.Errorf("addfeed command expects a name and a URL argument")
-	}
 	feedName := cmd.args[0]
 	feedUrl := cmd.args[1]
 
@@ -233,9 +231,25 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("error creating new feed follow: %v", err)
 	}
 
-	fmt.Printf("Feed created: %+v\n", createdFeed)
-	fmt.Printf("Followed by: %s\n", createdFeedFollow.UserName)
-	return nil
+	record := feedRecord{
+		ID:        createdFeed.ID.String(),
+		Name:      createdFeed.Name,
+		Url:       createdFeed.Url,
+		CreatedBy: user.Name,
+	}
+	return s.out.emit(record, func() {
+		fmt.Printf("Feed created: %+v\n", createdFeed)
+		fmt.Printf("Followed by: %s\n", createdFeedFollow.UserName)
+	})
+}
+
+type feedRecord struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Url       string  `json:"url"`
+	Title     *string `json:"title"`
+	Site      *string `json:"site"`
+	CreatedBy string  `json:"created_by"`
 }
 
 func handlerFeeds(s *state, cmd command) error {
@@ -245,17 +259,118 @@ func handlerFeeds(s *state, cmd command) error {
 	}
 
 	for _, feed := range feeds {
-		fmt.Printf("Feed Name: %s\n", feed.FeedName)
-		fmt.Printf("Feed URL: %s\n", feed.Url)
-		fmt.Printf("Created by: %s\n\n", feed.UserName)
+		record := feedRecord{
+			ID:        feed.ID.String(),
+			Name:      feed.FeedName,
+			Url:       feed.Url,
+			Title:     optionalString(feed.SiteTitle),
+			Site:      optionalString(feed.SiteLink),
+			CreatedBy: feed.UserName,
+		}
+		err := s.out.emit(record, func() {
+			fmt.Printf("Feed Name: %s\n", feed.FeedName)
+			fmt.Printf("Feed URL: %s\n", feed.Url)
+			if feed.SiteTitle.Valid {
+				fmt.Printf("Title: %s\n", feed.SiteTitle.String)
+			}
+			if feed.SiteLink.Valid {
+				fmt.Printf("Site: %s\n", feed.SiteLink.String)
+			}
+			fmt.Printf("Created by: %s\n\n", feed.UserName)
+		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("follow command expects a URL argument")
+type feedInfoRecord struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Url              string     `json:"url"`
+	Title            *string    `json:"title"`
+	Description      *string    `json:"description"`
+	Site             *string    `json:"site"`
+	Language         *string    `json:"language"`
+	Image            *string    `json:"image"`
+	Generator        *string    `json:"generator"`
+	CreatedBy        string     `json:"created_by"`
+	LastFetchedAt    *time.Time `json:"last_fetched_at"`
+	DeadAt           *time.Time `json:"dead_at"`
+	NextFetchAt      *time.Time `json:"next_fetch_at"`
+	ParseWarning     *string    `json:"parse_warning"`
+	FetchFullContent bool       `json:"fetch_full_content"`
+}
+
+func handlerFeedInfo(s *state, cmd command) error {
+	feedUrl := cmd.args[0]
+
+	feed, err := s.db.GetFeedInfoByUrl(context.Background(), feedUrl)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("feed with URL %s does not exist", feedUrl)
+	} else if err != nil {
+		return fmt.Errorf("error getting feed by URL: %v", err)
+	}
+
+	record := feedInfoRecord{
+		ID:               feed.ID.String(),
+		Name:             feed.Name,
+		Url:              feed.Url,
+		Title:            optionalString(feed.SiteTitle),
+		Description:      optionalString(feed.SiteDescription),
+		Site:             optionalString(feed.SiteLink),
+		Language:         optionalString(feed.Language),
+		Image:            optionalString(feed.ImageUrl),
+		Generator:        optionalString(feed.Generator),
+		CreatedBy:        feed.UserName,
+		LastFetchedAt:    optionalTime(feed.LastFetchedAt),
+		DeadAt:           optionalTime(feed.DeadAt),
+		NextFetchAt:      optionalTime(feed.NextFetchAt),
+		ParseWarning:     optionalString(feed.ParseWarning),
+		FetchFullContent: feed.FetchFullContent,
 	}
+	return s.out.emit(record, func() {
+		// Print optional metadata only when the feed has provided it
+		optional := func(label string, value sql.NullString) {
+			if value.Valid {
+				fmt.Printf("%s: %s\n", label, value.String)
+			}
+		}
+
+		fmt.Printf("Feed Name: %s\n", feed.Name)
+		fmt.Printf("Feed URL: %s\n", feed.Url)
+		optional("Title", feed.SiteTitle)
+		optional("Description", feed.SiteDescription)
+		optional("Site", feed.SiteLink)
+		optional("Language", feed.Language)
+		optional("Image", feed.ImageUrl)
+		optional("Generator", feed.Generator)
+		fmt.Printf("Created by: %s\n", feed.UserName)
+		if feed.LastFetchedAt.Valid {
+			fmt.Printf("Last fetched at: %s\n", feed.LastFetchedAt.Time.Format(time.RFC1123))
+		} else {
+			fmt.Printf("Last fetched at: never\n")
+		}
+		if feed.DeadAt.Valid {
+			fmt.Printf("Status: gone since %s\n", feed.DeadAt.Time.Format(time.RFC1123))
+		} else if feed.NextFetchAt.Valid && feed.NextFetchAt.Time.After(time.Now()) {
+			fmt.Printf("Status: deferred until %s\n", feed.NextFetchAt.Time.Format(time.RFC1123))
+		}
+		optional("Parse warning", feed.ParseWarning)
+		if feed.FetchFullContent {
+			fmt.Printf("Full content: fetched from each post's page\n")
+		}
+	})
+}
+
+type followRecord struct {
+	FeedID   string `json:"feed_id"`
+	FeedName string `json:"feed_name"`
+	UserName string `json:"user_name"`
+}
+
+func handlerFollow(s *state, cmd command, user database.User) error {
 	feedUrl := cmd.args[0]
 
 	// Get the feed by URL
@@ -278,9 +393,15 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("error creating new feed follow: %v", err)
 	}
 
-	fmt.Printf("Feed: %s\n", createdFeedFollow.FeedName)
-	fmt.Printf("Followed by: %s\n", createdFeedFollow.UserName)
-	return nil
+	record := followRecord{
+		FeedID:   createdFeedFollow.FeedID.String(),
+		FeedName: createdFeedFollow.FeedName,
+		UserName: createdFeedFollow.UserName,
+	}
+	return s.out.emit(record, func() {
+		fmt.Printf("Feed: %s\n", createdFeedFollow.FeedName)
+		fmt.Printf("Followed by: %s\n", createdFeedFollow.UserName)
+	})
 }
 
 func handlerFollowing(s *state, cmd command, user database.User) error {
@@ -291,15 +412,22 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 	}
 
 	for _, follow := range feedFollows {
-		fmt.Printf("Feed Name: %s\n", follow.FeedName)
+		record := followRecord{
+			FeedID:   follow.FeedID.String(),
+			FeedName: follow.FeedName,
+			UserName: follow.UserName,
+		}
+		err := s.out.emit(record, func() {
+			fmt.Printf("Feed Name: %s\n", follow.FeedName)
+		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) < 1 {
-		return fmt.Errorf("unfollow command expects a URL argument")
-	}
 	feedUrl := cmd.args[0]
 
 	// Delete the feed follow record
@@ -325,23 +453,58 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 		}
 	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
+	filters, err := loadPostFilters(context.Background(), s, user.ID)
 	if err != nil {
-		return fmt.Errorf("error getting posts for user: %v", err)
+		return err
 	}
 
-	for _, post := range posts {
-		fmt.Printf("Title: %s\n", post.Title)
-		fmt.Printf("URL: %s\n", post.Url)
-		if post.Description.Valid {
-			fmt.Printf("Description: %s\n", post.Description.String)
-		} else {
-			fmt.Printf("Description: NULL\n")
+	// Page through the posts until enough of them survive the user's filters
+	shown := 0
+	for offset := 0; shown < limit; offset += limit {
+		posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+			UserID: user.ID,
+			Limit:  int32(limit),
+			Offset: int32(offset),
+		})
+		if err != nil {
+			return fmt.Errorf("error getting posts for user: %v", err)
+		}
+
+		for _, post := range posts {
+			hidden, highlighted := filters.apply(post)
+			if hidden {
+				continue
+			}
+			if shown == limit {
+				break
+			}
+			shown++
+
+			err := s.out.emit(newPostRecord(post, highlighted), func() {
+				if highlighted {
+					fmt.Printf("Title: * %s *\n", post.Title)
+				} else {
+					fmt.Printf("Title: %s\n", post.Title)
+				}
+				fmt.Printf("ID: %s\n", shortPostID(post.ID))
+				fmt.Printf("URL: %s\n", post.Url)
+				if post.Content.Valid {
+					fmt.Printf("Content:\n%s\n", content.RenderText(post.Content.String, terminalWidth()))
+				} else if post.Description.Valid {
+					fmt.Printf("Description:\n%s\n", content.RenderText(post.Description.String, terminalWidth()))
+				} else {
+					fmt.Printf("Description: NULL\n")
+				}
+				fmt.Printf("Published At: %s\n\n", post.PublishedAt.Time)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(posts) < limit {
+			break
 		}
-		fmt.Printf("Published At: %s\n\n", post.PublishedAt.Time)
 	}
 
 	return nil
@@ -351,36 +514,119 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 	return func(s *state, cmd command) error {
 		// Get the current user from the config
 		currentUser := s.cfg.CurrentUserName
+		if currentUser == "" {
+			return fmt.Errorf("not logged in: run \"gator login <name>\" or \"gator register <name>\"")
+		}
 		user, err := s.db.GetUserByName(context.Background(), currentUser)
 		if err != nil {
 			return fmt.Errorf("error getting current user: %v", err)
 		}
+		if err := checkSession(context.Background(), s, user); err != nil {
+			return err
+		}
 
 		// Call the handler with the user
 		return handler(s, cmd, user)
 	}
 }
 
-func scrapeFeeds(s *state) error {
+// terminalWidth returns the width of the terminal attached to stdout, falling back
+// to $COLUMNS and then to 80 columns when the output is not a terminal
+func terminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 80
+}
+
+// nullString converts an optional string to sql.NullString, treating "" as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// scrapeFeeds fetches up to n of the feeds that are due, concurrently.
+// Requests to the same host are throttled by the shared fetcher.
+func scrapeFeeds(s *state, n int) error {
 	ctx := context.Background()
 
-	// Get the next feed to fetch
-	feed, err := s.db.GetNextFeedToFetch(ctx)
+	// Get the next feeds to fetch
+	feeds, err := s.db.GetNextFeedsToFetch(ctx, int32(n))
 	if err != nil {
-		return fmt.Errorf("error getting next feed to fetch: %v", err)
+		return fmt.Errorf("error getting next feeds to fetch: %v", err)
 	}
 
-	// Mark the feed as fetched
-	err = s.db.MarkFeedFetched(ctx, feed.ID)
-	if err != nil {
-		return fmt.Errorf("error marking feed as fetched: %v", err)
+	// Mark the feeds as fetched before starting so they are not picked up twice
+	for _, feed := range feeds {
+		err = s.db.MarkFeedFetched(ctx, feed.ID)
+		if err != nil {
+			return fmt.Errorf("error marking feed as fetched: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func(feed database.Feed) {
+			defer wg.Done()
+			if err := scrapeFeed(ctx, s, feed); err != nil {
+				log.Printf("%s: %v", feed.Url, err)
+			}
+		}(feed)
 	}
+	wg.Wait()
 
+	return nil
+}
+
+// scrapeFeed fetches a single feed and saves its posts
+func scrapeFeed(ctx context.Context, s *state, feed database.Feed) error {
 	// Fetch the feed using the URL
-	feedData, err := fetchFeed(ctx, feed.Url)
+	resp, err := s.fetcher.fetchFeed(ctx, feed.Url)
 	if err != nil {
+		handleFetchError(ctx, s, feed, err)
 		return fmt.Errorf("error fetching feed: %v", err)
 	}
+	feedData := resp.Feed
+
+	// Follow permanent redirects by updating the stored URL
+	feedID := feed.ID
+	if resp.Moved && resp.FinalURL != feed.Url {
+		feedID, err = relocateFeed(ctx, s, feed.ID, feed.Url, resp.FinalURL)
+		if err != nil {
+			log.Printf("error relocating feed %s: %v", feed.Url, err)
+		} else {
+			log.Printf("feed %s moved permanently to %s", feed.Url, resp.FinalURL)
+		}
+	}
+
+	// Store the channel metadata extracted from the feed
+	err = s.db.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:              feedID,
+		SiteTitle:       nullString(feedData.Channel.Title),
+		SiteDescription: nullString(content.RenderText(feedData.Channel.Description, 0)),
+		SiteLink:        nullString(feedData.Channel.Link()),
+		Language:        nullString(strings.TrimSpace(feedData.Channel.Language)),
+		ImageUrl:        nullString(feedData.Channel.ImageURL()),
+		Generator:       nullString(strings.TrimSpace(feedData.Channel.Generator)),
+	})
+	if err != nil {
+		log.Printf("error updating feed metadata: %v", err)
+	}
+
+	// Record or clear the parse warning for malformed feeds
+	if resp.Warning != "" {
+		log.Printf("%s: %s", feed.Url, resp.Warning)
+	}
+	err = s.db.SetFeedParseWarning(ctx, database.SetFeedParseWarningParams{
+		ID:           feedID,
+		ParseWarning: nullString(resp.Warning),
+	})
+	if err != nil {
+		log.Printf("error recording parse warning: %v", err)
+	}
 
 	// Iterate over the items in the feed and save them to the database
 	for _, item := range feedData.Channel.Items {
@@ -396,8 +642,9 @@ func scrapeFeeds(s *state) error {
 			}
 		}
 
-		// Convert description to sql.NullString
-		description := sql.NullString{String: item.Description, Valid: item.Description != ""}
+		// Sanitize the description HTML and convert it to sql.NullString
+		sanitized := content.Sanitize(item.Description)
+		description := sql.NullString{String: sanitized, Valid: sanitized != ""}
 
 		// Create a new post
 		newPost := database.CreatePostParams{
@@ -408,88 +655,386 @@ func scrapeFeeds(s *state) error {
 			Url:         item.Link,
 			Description: description,
 			PublishedAt: sql.NullTime{Time: publishedAt, Valid: true},
-			FeedID:      feed.ID,
+			FeedID:      feedID,
+			Author:      nullString(item.AuthorName()),
+			Categories:  item.CategoryNames(),
 		}
 
-		_, err = s.db.CreatePost(ctx, newPost)
+		createdPost, err := s.db.CreatePost(ctx, newPost)
 		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" { // unique_violation
+			if errors.Is(err, store.ErrConflict) {
 				log.Printf("post with URL %s already exists, ignoring", item.Link)
 				continue
 			}
 			log.Printf("error creating new post: %v", err)
+			continue
 		}
-	}
-
-	return nil
-}
 
-func main() {
-	// Read the config file
-	cfg, err := config.Read()
-	if err != nil {
-		log.Fatalf("Error reading config file: %v", err)
-	}
+		// Store podcast episodes and other attached media
+		for _, enclosure := range item.Enclosures {
+			if enclosure.URL == "" {
+				continue
+			}
+			length := sql.NullInt64{}
+			if n, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && n > 0 {
+				length = sql.NullInt64{Int64: n, Valid: true}
+			}
+			err := s.db.CreatePostEnclosure(ctx, database.CreatePostEnclosureParams{
+				ID:       uuid.New(),
+				PostID:   createdPost.ID,
+				Url:      enclosure.URL,
+				MimeType: nullString(enclosure.Type),
+				Length:   length,
+			})
+			if err != nil {
+				log.Printf("error creating enclosure for %s: %v", createdPost.Url, err)
+			}
+		}
 
-	// Open a connection to the database
-	db, err := sql.Open("postgres", cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Error opening database connection: %v", err)
+		// Download the linked article for feeds that only ship a summary
+		if feed.FetchFullContent {
+			if err := storeFullContent(ctx, s, createdPost); err != nil {
+				log.Printf("%s: %v", createdPost.Url, err)
+			}
+		}
 	}
-	defer db.Close()
 
-	// Create a new instance of database.Queries
-	dbQueries := database.New(db)
-
-	// Create a new state instance
-	s := &state{db: dbQueries, cfg: &cfg}
+	return nil
+}
 
-	// Create a new commands instance with an initialized map of handler functions
-	cmds := &commands{handlers: make(map[string]func(*state, command) error)}
+// registerCommands adds every gator command to the registry
+func registerCommands(cmds *commands) {
+	postArg := []argSpec{{name: "post"}}
+	feedArg := []argSpec{{name: "url", complete: completeFeed}}
+	followedFeedArg := []argSpec{{name: "url", complete: completeFollowedFeed}}
+
+	cmds.register(commandSpec{
+		name:        "help",
+		description: "Show the list of commands or the help for one command",
+		args:        []argSpec{{name: "command", optional: true, complete: completeCommand}},
+		handler: func(s *state, cmd command) error {
+			if len(cmd.args) == 0 {
+				cmds.printHelp(os.Stdout)
+				return nil
+			}
+			spec, exists := cmds.specs[cmd.args[0]]
+			if !exists {
+				return fmt.Errorf("unknown command: %s", cmd.args[0])
+			}
+			spec.printHelp(os.Stdout)
+			return nil
+		},
+		offline:    true,
+		standalone: true,
+	})
+	cmds.register(commandSpec{
+		name:        "completion",
+		description: "Print the shell completion script for bash, zsh or fish",
+		args:        []argSpec{{name: "shell", choices: completionShells}},
+		handler:     handlerCompletion,
+		offline:     true,
+		standalone:  true,
+	})
+	cmds.register(commandSpec{
+		name:        "shell",
+		description: "Run commands interactively without restarting gator",
+		handler:     handlerShell(cmds),
+		offline:     true,
+	})
+	cmds.register(commandSpec{
+		name:        "__complete",
+		description: "List completion candidates for a partial command line",
+		args:        []argSpec{{name: "line", optional: true}},
+		handler:     handlerComplete(cmds),
+		hidden:      true,
+		offline:     true,
+		standalone:  true,
+	})
 
-	// Register the login handler function
-	cmds.register("login", handlerLogin)
+	cmds.register(commandSpec{
+		name:        "init",
+		description: "Create a config file interactively",
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("force", false, "replace an existing config file")
+		},
+		handler:    handlerInit,
+		offline:    true,
+		standalone: true,
+	})
+	cmds.register(commandSpec{
+		name:        "profile",
+		description: "List, switch, add or remove config profiles",
+		args: []argSpec{
+			{name: "action", choices: []string{"list", "use", "add", "remove"}},
+			{name: "name", optional: true, complete: completeProfile},
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.String("db-url", "", "database `url` of the new profile")
+			fs.String("user", "", "current user `name` of the new profile")
+		},
+		flagCompletions: map[string]string{"user": completeUser},
+		handler:         handlerProfile,
+		offline:         true,
+		standalone:      true,
+	})
+	cmds.register(commandSpec{
+		name:        "migrate",
+		description: "Apply or roll back database migrations, or show their status",
+		args:        []argSpec{{name: "direction", choices: []string{"up", "down", "status"}}},
+		handler:     handlerMigrate,
+		offline:     true,
+	})
 
-	// Register the register handler function
-	cmds.register("register", handlerRegister)
+	// Users
+	cmds.register(commandSpec{
+		name:        "login",
+		description: "Log in as an existing user",
+		args:        []argSpec{{name: "username", complete: completeUser}},
+		handler:     handlerLogin,
+	})
+	cmds.register(commandSpec{
+		name:        "register",
+		description: "Create a user and log in as them",
+		args:        []argSpec{{name: "username"}},
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("no-password", false, "create the user without a password and without asking for one")
+		},
+		handler: handlerRegister,
+	})
+	cmds.register(commandSpec{
+		name:        "logout",
+		description: "End the current session",
+		handler:     handlerLogout,
+	})
+	cmds.register(commandSpec{
+		name:        "passwd",
+		description: "Set, change or remove the current user's password",
+		handler:     middlewareLoggedIn(handlerPasswd),
+	})
+	cmds.register(commandSpec{
+		name:        "reset",
+		description: "Delete all users, feeds and posts",
+		handler:     middlewareLoggedIn(handlerReset),
+	})
+	cmds.register(commandSpec{
+		name:        "users",
+		description: "List all users",
+		handler:     handlerUsers,
+	})
 
-	// Register the reset handler function
-	cmds.register("reset", handlerReset)
+	// Fetching
+	cmds.register(commandSpec{
+		name:        "agg",
+		description: "Fetch feeds continuously, waiting the given interval (e.g. 1m) between rounds",
+		args:        []argSpec{{name: "interval"}, {name: "concurrency", optional: true}},
+		handler:     handlerAgg,
+	})
+	cmds.register(commandSpec{
+		name:        "prune",
+		description: "Delete posts that exceed the retention policy",
+		handler:     handlerPrune,
+	})
 
-	// Register the users handler function
-	cmds.register("users", handlerUsers)
+	// Feeds
+	cmds.register(commandSpec{
+		name:        "addfeed",
+		description: "Add a feed and follow it",
+		args:        []argSpec{{name: "name"}, {name: "url"}},
+		handler:     middlewareLoggedIn(handlerAddFeed),
+	})
+	cmds.register(commandSpec{
+		name:        "feeds",
+		description: "List all feeds",
+		handler:     handlerFeeds,
+	})
+	cmds.register(commandSpec{
+		name:        "feed-info",
+		description: "Show a feed's metadata and fetch status",
+		args:        feedArg,
+		handler:     handlerFeedInfo,
+	})
+	cmds.register(commandSpec{
+		name:        "feed-revive",
+		description: "Resume fetching a feed that was marked as gone",
+		args:        feedArg,
+		handler:     handlerReviveFeed,
+	})
+	cmds.register(commandSpec{
+		name:        "feed-fulltext",
+		description: "Turn fetching of full article content on or off for a feed",
+		args:        []argSpec{{name: "url", complete: completeFeed}, {name: "state", choices: []string{"on", "off"}}},
+		handler:     handlerFeedFullText,
+	})
+	cmds.register(commandSpec{
+		name:        "feed-retention",
+		description: "Override the retention policy for a feed, or restore the default",
+		args:        []argSpec{{name: "url", complete: completeFeed}, {name: "days|default"}, {name: "posts|default"}},
+		handler:     handlerFeedRetention,
+	})
+	cmds.register(commandSpec{
+		name:        "follow",
+		description: "Follow a feed",
+		args:        feedArg,
+		handler:     middlewareLoggedIn(handlerFollow),
+	})
+	cmds.register(commandSpec{
+		name:        "following",
+		description: "List the feeds you follow",
+		handler:     middlewareLoggedIn(handlerFollowing),
+	})
+	cmds.register(commandSpec{
+		name:        "unfollow",
+		description: "Stop following a feed",
+		args:        followedFeedArg,
+		handler:     middlewareLoggedIn(handlerUnfollow),
+	})
 
-	// Register the agg handler function
-	cmds.register("agg", handlerAgg)
+	// Reading
+	cmds.register(commandSpec{
+		name:        "browse",
+		description: "List the newest posts from the feeds you follow",
+		args:        []argSpec{{name: "limit", optional: true}},
+		handler:     middlewareLoggedIn(handlerBrowse),
+	})
+	cmds.register(commandSpec{
+		name:        "show",
+		description: "Print a post in full and mark it as read",
+		args:        postArg,
+		handler:     middlewareLoggedIn(handlerShow),
+	})
+	cmds.register(commandSpec{
+		name:        "open",
+		description: "Open a post in the browser and mark it as read",
+		args:        postArg,
+		handler:     middlewareLoggedIn(handlerOpen),
+	})
+	cmds.register(commandSpec{
+		name:        "save",
+		description: "Save a post so it is never pruned",
+		args:        postArg,
+		handler:     middlewareLoggedIn(handlerSave),
+	})
+	cmds.register(commandSpec{
+		name:        "unsave",
+		description: "Remove a post from your saved posts",
+		args:        postArg,
+		handler:     middlewareLoggedIn(handlerUnsave),
+	})
+	cmds.register(commandSpec{
+		name:        "tui",
+		description: "Read posts in a full-screen terminal interface",
+		handler:     middlewareLoggedIn(handlerTUI),
+	})
 
-	// Register the addfeed handler function with middleware
-	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
+	// Filters
+	cmds.register(commandSpec{
+		name:        "addfilter",
+		description: "Hide (exclude) or highlight (include) posts matching a pattern",
+		args: []argSpec{
+			{name: "action", choices: []string{filterActionInclude, filterActionExclude}},
+			{name: "field", choices: filterFields},
+			{name: "pattern"},
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("regex", false, "treat the pattern as a regular expression")
+			fs.String("feed", "", "only apply the filter to the feed with this `url`")
+		},
+		flagCompletions: map[string]string{"feed": completeFollowedFeed},
+		handler:         middlewareLoggedIn(handlerAddFilter),
+	})
+	cmds.register(commandSpec{
+		name:        "filters",
+		description: "List your filters",
+		handler:     middlewareLoggedIn(handlerFilters),
+	})
+	cmds.register(commandSpec{
+		name:        "removefilter",
+		description: "Delete one of your filters",
+		args:        []argSpec{{name: "filter-id"}},
+		handler:     middlewareLoggedIn(handlerRemoveFilter),
+	})
+}
 
-	// Register the feeds handler function
-	cmds.register("feeds", handlerFeeds)
+// openStore connects to the database named by the database URL. A URL
+// starting with "sqlite:" names a SQLite database file, such as
+// sqlite:~/.gator.db, and anything else is passed to the PostgreSQL driver.
+func openStore(databaseURL string) (store.Store, *sql.DB, *migrate.Dialect, error) {
+	path, isSQLite := strings.CutPrefix(databaseURL, "sqlite:")
+	if !isSQLite {
+		db, err := sql.Open("postgres", databaseURL)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return store.NewPostgres(db), db, migrate.Postgres, nil
+	}
 
-	// Register the follow handler function with middleware
-	cmds.register("follow", middlewareLoggedIn(handlerFollow))
+	path = strings.TrimPrefix(path, "//")
+	if path == "" {
+		return nil, nil, nil, fmt.Errorf("database URL %q has no file path", databaseURL)
+	}
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		path = filepath.Join(home, rest)
+	}
+	db, err := store.OpenSQLite(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return store.NewSQLite(db), db, migrate.SQLite, nil
+}
 
-	// Register the following handler function with middleware
-	cmds.register("following", middlewareLoggedIn(handlerFollowing))
+func main() {
+	// Create a new commands instance and register every command
+	cmds := &commands{specs: make(map[string]commandSpec)}
+	registerCommands(cmds)
+
+	// Options such as --config come before the command name
+	opts, args, err := parseOptions(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		cmds.printHelp(os.Stdout)
+		return
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if len(args) < 1 {
+		cmds.printHelp(os.Stderr)
+		os.Exit(1)
+	}
+	cmd := command{name: args[0], args: args[1:]}
+	spec, exists := cmds.specs[cmd.name]
 
-	// Register the unfollow handler function with middleware
-	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
+	// Read the config file. Standalone commands such as init and help also
+	// run without one.
+	path, err := config.Path(opts.configPath)
+	if err != nil {
+		log.Fatalf("Error locating config file: %v", err)
+	}
+	cfg, err := config.Read(path, opts.profile)
+	s := &state{cfg: &cfg}
+	if err != nil && !(exists && spec.standalone) {
+		log.Fatalf("Error: %v", err)
+	}
 
-	// Register the browse handler function with middleware
-	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	if err == nil {
+		// Open a connection to the database
+		dbStore, db, dialect, err := openStore(cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Error opening database connection: %v", err)
+		}
+		defer db.Close()
+		s.db, s.conn, s.dialect = dbStore, db, dialect
 
-	// Use os.Args to get the command-line arguments passed in by the user
-	if len(os.Args) < 2 {
-		log.Fatalf("Error: expected at least 2 arguments, got %d", len(os.Args))
+		// Create the shared HTTP client used to fetch feeds
+		s.fetcher, err = newFetcher(cfg.Fetch)
+		if err != nil {
+			log.Fatalf("Error configuring feed fetching: %v", err)
+		}
 	}
 
-	// Split the command-line arguments into the command name and the arguments slice
-	cmdName := os.Args[1]
-	cmdArgs := os.Args[2:]
-	cmd := command{name: cmdName, args: cmdArgs}
-
 	// Run the given command and print any errors returned
 	if err := cmds.run(s, cmd); err != nil {
 		log.Fatalf("Error running command: %v", err)
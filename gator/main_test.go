@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sushiqiren/gator/internal/config"
+	"github.com/sushiqiren/gator/internal/database"
+	"github.com/sushiqiren/gator/internal/store"
+)
+
+// newTestState returns a state backed by the in-memory store, with a config
+// file in a temporary directory
+func newTestState(t *testing.T) (*state, *commands) {
+	t.Helper()
+	for _, name := range []string{"GATOR_CONFIG", "GATOR_DB_URL", "GATOR_USER", "GATOR_PROFILE"} {
+		t.Setenv(name, "")
+	}
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := config.Create(path, config.Config{Profile: config.Profile{DatabaseURL: "memory"}}, false)
+	if err != nil {
+		t.Fatalf("error creating config: %v", err)
+	}
+	cfg, err := config.Read(path, "")
+	if err != nil {
+		t.Fatalf("error reading config: %v", err)
+	}
+	fetcher, err := newFetcher(config.FetchConfig{PerHostInterval: "1ms"})
+	if err != nil {
+		t.Fatalf("error creating fetcher: %v", err)
+	}
+
+	cmds := &commands{specs: make(map[string]commandSpec)}
+	registerCommands(cmds)
+	return &state{db: store.NewMemory(), cfg: &cfg, fetcher: fetcher}, cmds
+}
+
+// runCommand runs a command line like commands.run, without the schema check
+// that needs a SQL database. It returns what the command wrote to its output.
+func runCommand(s *state, cmds *commands, args ...string) (string, error) {
+	spec, exists := cmds.specs[args[0]]
+	if !exists {
+		return "", fmt.Errorf("unknown command: %s", args[0])
+	}
+	cmd, err := spec.parse(args[1:])
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	out, err := newOutput(cmd.stringFlag("output"), &buf)
+	if err != nil {
+		return "", err
+	}
+	s.out = out
+	if err := spec.handler(s, cmd); err != nil {
+		return buf.String(), err
+	}
+	err = out.flush()
+	return buf.String(), err
+}
+
+// mustRun runs a command line and fails the test if it returns an error
+func mustRun(t *testing.T, s *state, cmds *commands, args ...string) string {
+	t.Helper()
+	out, err := runCommand(s, cmds, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+// browseTitles returns the titles of the posts browse shows
+func browseTitles(t *testing.T, s *state, cmds *commands) []string {
+	t.Helper()
+	var records []postRecord
+	out := mustRun(t, s, cmds, "browse", "10", "-o", "json")
+	if err := json.Unmarshal([]byte(out), &records); err != nil {
+		t.Fatalf("error decoding browse output %q: %v", out, err)
+	}
+	titles := []string{}
+	for _, record := range records {
+		titles = append(titles, record.Title)
+	}
+	return titles
+}
+
+func TestUserCommands(t *testing.T) {
+	s, cmds := newTestState(t)
+
+	steps := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"following"}, wantErr: "not logged in"},
+		{args: []string{"login", "alice"}, wantErr: "does not exist"},
+		{args: []string{"register", "alice", "--no-password"}},
+		{args: []string{"register", "alice", "--no-password"}, wantErr: "already exists"},
+		{args: []string{"register", "bob", "--no-password"}},
+		{args: []string{"following"}},
+		{args: []string{"login", "alice"}},
+	}
+	for _, step := range steps {
+		_, err := runCommand(s, cmds, step.args...)
+		switch {
+		case step.wantErr == "" && err != nil:
+			t.Fatalf("%v: %v", step.args, err)
+		case step.wantErr != "" && (err == nil || !strings.Contains(err.Error(), step.wantErr)):
+			t.Fatalf("%v: got error %v, want one containing %q", step.args, err, step.wantErr)
+		}
+	}
+
+	var users []userRecord
+	out := mustRun(t, s, cmds, "users", "-o", "json")
+	if err := json.Unmarshal([]byte(out), &users); err != nil {
+		t.Fatalf("error decoding users output %q: %v", out, err)
+	}
+	want := []userRecord{{Name: "alice", Current: true}, {Name: "bob", Current: false}}
+	if !slices.Equal(users, want) {
+		t.Errorf("users = %+v, want %+v", users, want)
+	}
+
+	// The login is saved to the config file
+	saved, err := config.Read(s.cfg.File(), "")
+	if err != nil {
+		t.Fatalf("error reading config: %v", err)
+	}
+	if saved.CurrentUserName != "alice" {
+		t.Errorf("config has user %q, want alice", saved.CurrentUserName)
+	}
+}
+
+const testFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Test feed</title>
+<link>http://example.com/</link>
+<item><title>A</title><link>http://example.com/a</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>C</title><link>http://example.com/c</link><pubDate>Wed, 03 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>B</title><link>http://example.com/b</link><pubDate>Tue, 02 Jan 2024 10:00:00 GMT</pubDate></item>
+<item><title>A again</title><link>http://example.com/a</link><pubDate>Mon, 01 Jan 2024 11:00:00 GMT</pubDate></item>
+<item><title>Undated</title><link>http://example.com/undated</link><pubDate>someday</pubDate></item>
+</channel></rss>`
+
+func TestScrapeAndBrowse(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeed)
+	}))
+	defer server.Close()
+	feedURL := server.URL + "/feed.xml"
+
+	s, cmds := newTestState(t)
+	mustRun(t, s, cmds, "register", "alice", "--no-password")
+	mustRun(t, s, cmds, "addfeed", "Test", feedURL)
+
+	if err := scrapeFeeds(s, 10); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+	// Duplicate links are stored once and undated items are skipped
+	if got, want := browseTitles(t, s, cmds), []string{"C", "B", "A"}; !slices.Equal(got, want) {
+		t.Errorf("browse = %v, want %v", got, want)
+	}
+
+	// Fetching again does not store the posts twice
+	feed, err := s.db.GetFeedByUrl(ctx, feedURL)
+	if err != nil {
+		t.Fatalf("GetFeedByUrl: %v", err)
+	}
+	posts, err := s.db.GetPostsByFeedID(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("GetPostsByFeedID: %v", err)
+	}
+	if err := scrapeFeed(ctx, s, database.Feed{ID: feed.ID, Url: feed.Url}); err != nil {
+		t.Fatalf("scrapeFeed: %v", err)
+	}
+	again, err := s.db.GetPostsByFeedID(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("GetPostsByFeedID: %v", err)
+	}
+	if len(again) != len(posts) {
+		t.Errorf("got %d posts after fetching again, want %d", len(again), len(posts))
+	}
+
+	info, err := s.db.GetFeedInfoByUrl(ctx, feedURL)
+	if err != nil {
+		t.Fatalf("GetFeedInfoByUrl: %v", err)
+	}
+	if info.SiteTitle.String != "Test feed" || info.SiteLink.String != "http://example.com/" {
+		t.Errorf("feed metadata = %q, %q", info.SiteTitle.String, info.SiteLink.String)
+	}
+
+	// A second user sees the posts only while following the feed
+	mustRun(t, s, cmds, "register", "bob", "--no-password")
+	if got := browseTitles(t, s, cmds); len(got) != 0 {
+		t.Errorf("browse before following = %v, want nothing", got)
+	}
+	mustRun(t, s, cmds, "follow", feedURL)
+	mustRun(t, s, cmds, "addfilter", "exclude", "title", "B")
+	if got, want := browseTitles(t, s, cmds), []string{"C", "A"}; !slices.Equal(got, want) {
+		t.Errorf("browse with a filter = %v, want %v", got, want)
+	}
+	mustRun(t, s, cmds, "unfollow", feedURL)
+	if got := browseTitles(t, s, cmds); len(got) != 0 {
+		t.Errorf("browse after unfollowing = %v, want nothing", got)
+	}
+}
+
+func TestScrapeSanitizesDescriptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{"escaped markup stays text", "use &amp;lt;div&amp;gt; here", "use &lt;div&gt; here"},
+		{"escaped html", "&lt;p&gt;Hi &lt;script&gt;alert(1)&lt;/script&gt;&lt;/p&gt;", "<p>Hi </p>"},
+		{"cdata html", "<![CDATA[<p onclick=\"x()\"><a href=\"javascript:x()\">link</a></p>]]>", "<p><a>link</a></p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `<rss version="2.0"><channel><title>T</title>
+<item><title>A</title><link>http://example.com/a</link><pubDate>Mon, 01 Jan 2024 10:00:00 GMT</pubDate><description>%s</description></item>
+</channel></rss>`, tt.description)
+			}))
+			defer server.Close()
+
+			s, cmds := newTestState(t)
+			mustRun(t, s, cmds, "register", "alice", "--no-password")
+			mustRun(t, s, cmds, "addfeed", "Test", server.URL)
+			if err := scrapeFeeds(s, 10); err != nil {
+				t.Fatalf("scrapeFeeds: %v", err)
+			}
+
+			post, err := s.db.GetPostByUrl(context.Background(), "http://example.com/a")
+			if err != nil {
+				t.Fatalf("GetPostByUrl: %v", err)
+			}
+			if post.Description.String != tt.want {
+				t.Errorf("description = %q, want %q", post.Description.String, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/sushiqiren/gator/internal/migrate"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var schemaFiles embed.FS
+
+// loadMigrations returns the schema migrations embedded in the binary for
+// the kind of database in use. SQLite has its own copy of the schema.
+func loadMigrations(d *migrate.Dialect) ([]migrate.Migration, error) {
+	root := "sql/schema"
+	if d == migrate.SQLite {
+		root = "sql/sqlite/schema"
+	}
+	dir, err := fs.Sub(schemaFiles, root)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Load(dir)
+}
+
+// checkSchema refuses to run commands against a database whose schema does
+// not match the migrations this binary was built with
+func checkSchema(ctx context.Context, s *state) error {
+	migrations, err := loadMigrations(s.dialect)
+	if err != nil {
+		return fmt.Errorf("error loading migrations: %v", err)
+	}
+	current, err := migrate.Version(ctx, s.conn, s.dialect)
+	if err != nil {
+		return fmt.Errorf("error checking database schema: %v", err)
+	}
+
+	latest := migrate.Latest(migrations)
+	switch {
+	case current == 0:
+		return fmt.Errorf("the database has not been set up yet: run \"gator migrate up\" to create the tables")
+	case current < latest:
+		return fmt.Errorf("the database schema is out of date (version %d, gator needs %d): run \"gator migrate up\"", current, latest)
+	case current > latest:
+		return fmt.Errorf("the database schema (version %d) is newer than this version of gator supports (%d): upgrade gator", current, latest)
+	}
+	return nil
+}
+
+type migrationRecord struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+func handlerMigrate(s *state, cmd command) error {
+	ctx := context.Background()
+	migrations, err := loadMigrations(s.dialect)
+	if err != nil {
+		return fmt.Errorf("error loading migrations: %v", err)
+	}
+
+	switch cmd.args[0] {
+	case "up":
+		applied, err := migrate.Up(ctx, s.conn, s.dialect, migrations)
+		for _, m := range applied {
+			fmt.Printf("Applied %s\n", m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Printf("The database is up to date\n")
+		}
+	case "down":
+		m, err := migrate.Down(ctx, s.conn, s.dialect, migrations)
+		if err != nil {
+			return err
+		}
+		if m == nil {
+			fmt.Printf("No migrations to roll back\n")
+		} else {
+			fmt.Printf("Rolled back %s\n", m.Name)
+		}
+	case "status":
+		statuses, err := migrate.Statuses(ctx, s.conn, s.dialect, migrations)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			record := migrationRecord{
+				Version: status.Version,
+				Name:    status.Name,
+				Applied: status.Applied,
+			}
+			if status.Applied {
+				record.AppliedAt = &status.AppliedAt
+			}
+			err := s.out.emit(record, func() {
+				if status.Applied {
+					fmt.Printf("%-40s applied %s\n", status.Name, status.AppliedAt.Format(time.RFC1123))
+				} else {
+					fmt.Printf("%-40s pending\n", status.Name)
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// outputFormats lists the values accepted by --output besides a template
+var outputFormats = []string{"text", "json", "ndjson", "csv"}
+
+// output writes the records emitted by a command in the format chosen with
+// the global --output flag. The text format prints the command's usual
+// human-readable output.
+type output struct {
+	format string
+	tmpl   *template.Template
+	w      io.Writer
+
+	// records are buffered for json, which prints a single array
+	records []any
+	csv     *csv.Writer
+	header  bool
+}
+
+// newOutput parses an --output value: one of outputFormats, or a Go
+// text/template such as "{{.Title}}" that is executed once per record
+func newOutput(spec string, w io.Writer) (*output, error) {
+	o := &output{format: spec, w: w}
+	switch spec {
+	case "", "text":
+		o.format = "text"
+	case "json", "ndjson":
+	case "csv":
+		o.csv = csv.NewWriter(w)
+	default:
+		if !strings.Contains(spec, "{{") {
+			return nil, fmt.Errorf("invalid output format %q: expected %s or a Go template", spec, strings.Join(outputFormats, ", "))
+		}
+		tmpl, err := template.New("output").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				data, err := json.Marshal(v)
+				return string(data), err
+			},
+			"join": strings.Join,
+		}).Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing output template: %v", err)
+		}
+		o.format = "template"
+		o.tmpl = tmpl
+	}
+	return o, nil
+}
+
+// emit writes one record. In text mode the record is ignored and text is
+// called to print the human-readable form instead.
+func (o *output) emit(record any, text func()) error {
+	switch o.format {
+	case "text":
+		text()
+	case "json":
+		o.records = append(o.records, record)
+	case "ndjson":
+		data, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("error encoding record: %v", err)
+		}
+		fmt.Fprintf(o.w, "%s\n", data)
+	case "csv":
+		names, cells, err := csvFields(record)
+		if err != nil {
+			return err
+		}
+		if !o.header {
+			if err := o.csv.Write(names); err != nil {
+				return err
+			}
+			o.header = true
+		}
+		if err := o.csv.Write(cells); err != nil {
+			return err
+		}
+	case "template":
+		if err := o.tmpl.Execute(o.w, record); err != nil {
+			return fmt.Errorf("error executing output template: %v", err)
+		}
+		fmt.Fprintln(o.w)
+	}
+	return nil
+}
+
+// flush writes any buffered records once the command has finished
+func (o *output) flush() error {
+	switch o.format {
+	case "json":
+		if o.records == nil {
+			o.records = []any{}
+		}
+		encoder := json.NewEncoder(o.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(o.records)
+	case "csv":
+		o.csv.Flush()
+		return o.csv.Error()
+	}
+	return nil
+}
+
+// csvFields returns the column names and cells of a record. The record is
+// encoded as JSON first so the columns always match the json and ndjson keys.
+// Lists of strings are joined with semicolons, other nested values are written
+// as JSON and null values are left empty.
+func csvFields(record any) ([]string, []string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding record: %v", err)
+	}
+
+	var names, cells []string
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if _, err := decoder.Token(); err != nil {
+		return nil, nil, err
+	}
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, key.(string))
+		cells = append(cells, csvCell(raw))
+	}
+	return names, cells, nil
+}
+
+func csvCell(raw json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, ";")
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// optionalString converts a nullable column for use in a record
+func optionalString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+// optionalTime converts a nullable timestamp for use in a record
+func optionalTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	defaultPerHostInterval    = time.Second
+	defaultPerHostConcurrency = 1
+	robotsCacheTTL            = 24 * time.Hour
+	robotsErrorCacheTTL       = time.Hour
+)
+
+// errRobotsDisallowed is returned when robots.txt forbids fetching a URL
+var errRobotsDisallowed = errors.New("fetching is disallowed by robots.txt")
+
+// hostKey groups URLs by registrable domain so that feeds living on subdomains of
+// a shared platform (e.g. alice.substack.com and bob.substack.com) share one budget
+func hostKey(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+// hostLimiter spaces out requests to the same host and caps how many run at once
+type hostLimiter struct {
+	interval    time.Duration
+	concurrency int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+type hostSlot struct {
+	sem  chan struct{}
+	next time.Time
+}
+
+func newHostLimiter(interval time.Duration, concurrency int) *hostLimiter {
+	return &hostLimiter{
+		interval:    interval,
+		concurrency: concurrency,
+		hosts:       make(map[string]*hostSlot),
+	}
+}
+
+// acquire blocks until a request to the host may start and returns a function
+// that must be called once the request has finished
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, l.concurrency)}
+		l.hosts[host] = slot
+	}
+	l.mu.Unlock()
+
+	select {
+	case slot.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-slot.sem }
+
+	// Reserve the next start time for this host
+	l.mu.Lock()
+	now := time.Now()
+	start := slot.next
+	if start.Before(now) {
+		start = now
+	}
+	slot.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// robotsRules are the Allow and Disallow rules that apply to gator on one origin
+type robotsRules struct {
+	allow    []string
+	disallow []string
+	expires  time.Time
+}
+
+// allowed applies the longest matching rule, with Allow winning ties
+func (r *robotsRules) allowed(path string) bool {
+	longestAllow, longestDisallow := -1, -1
+	for _, pattern := range r.allow {
+		if robotsPatternMatches(pattern, path) && len(pattern) > longestAllow {
+			longestAllow = len(pattern)
+		}
+	}
+	for _, pattern := range r.disallow {
+		if robotsPatternMatches(pattern, path) && len(pattern) > longestDisallow {
+			longestDisallow = len(pattern)
+		}
+	}
+	return longestDisallow < 0 || longestAllow >= longestDisallow
+}
+
+// robotsPatternMatches matches a robots.txt path pattern supporting the * and $ wildcards
+func robotsPatternMatches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	if !anchored && !strings.Contains(pattern, "*") {
+		return strings.HasPrefix(path, pattern)
+	}
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// parseRobots extracts the rules for the given user agent token, falling back
+// to the rules for "*" when no group names the agent
+func parseRobots(body []byte, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+
+	var specific, wildcard *robotsRules
+	var current []*robotsRules
+	inAgents := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive User-agent lines share the group that follows them
+			if !inAgents {
+				current = nil
+			}
+			inAgents = true
+			name := strings.ToLower(value)
+			if name == "*" {
+				if wildcard == nil {
+					wildcard = &robotsRules{}
+				}
+				current = append(current, wildcard)
+			} else if strings.Contains(agent, name) {
+				if specific == nil {
+					specific = &robotsRules{}
+				}
+				current = append(current, specific)
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if value == "" {
+				continue
+			}
+			for _, rules := range current {
+				if key == "allow" {
+					rules.allow = append(rules.allow, value)
+				} else {
+					rules.disallow = append(rules.disallow, value)
+				}
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	if specific != nil {
+		return specific
+	}
+	if wildcard != nil {
+		return wildcard
+	}
+	return &robotsRules{}
+}
+
+// robotsCache remembers the robots.txt rules of every origin gator has talked to
+type robotsCache struct {
+	mu    sync.Mutex
+	rules map[string]*robotsRules
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{rules: make(map[string]*robotsRules)}
+}
+
+// allowed reports whether robots.txt on the URL's origin permits gator to fetch it
+func (c *robotsCache) allowed(ctx context.Context, f *fetcher, u *url.URL) bool {
+	origin := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	rules, ok := c.rules[origin]
+	c.mu.Unlock()
+
+	if !ok || time.Now().After(rules.expires) {
+		res, err := f.do(ctx, origin+"/robots.txt", "text/plain")
+		var statusErr *fetchStatusError
+		switch {
+		case err == nil:
+			rules = parseRobots(res.Body, "gator")
+			rules.expires = time.Now().Add(robotsCacheTTL)
+		case errors.As(err, &statusErr) && statusErr.StatusCode >= 400 && statusErr.StatusCode < 500 && statusErr.StatusCode != http.StatusTooManyRequests:
+			// A missing robots.txt allows everything
+			rules = &robotsRules{expires: time.Now().Add(robotsCacheTTL)}
+		default:
+			// An unreachable robots.txt disallows everything until it is
+			// checked again (RFC 9309, section 2.3.1.4)
+			rules = &robotsRules{disallow: []string{"/"}, expires: time.Now().Add(robotsErrorCacheTTL)}
+		}
+
+		c.mu.Lock()
+		c.rules[origin] = rules
+		c.mu.Unlock()
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return rules.allowed(path)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sushiqiren/gator/internal/config"
+)
+
+func TestRobotsCacheAllowed(t *testing.T) {
+	tests := []struct {
+		name string
+		// status is the robots.txt response, or 0 when the server is down
+		status int
+		body   string
+		want   map[string]bool
+	}{
+		{
+			name:   "rules",
+			status: http.StatusOK,
+			body:   "User-agent: *\nDisallow: /private\n",
+			want:   map[string]bool{"/feed.xml": true, "/private/feed.xml": false},
+		},
+		{
+			name:   "missing",
+			status: http.StatusNotFound,
+			want:   map[string]bool{"/feed.xml": true, "/private/feed.xml": true},
+		},
+		{
+			name:   "server error",
+			status: http.StatusServiceUnavailable,
+			want:   map[string]bool{"/feed.xml": false, "/private/feed.xml": false},
+		},
+		{
+			name:   "rate limited",
+			status: http.StatusTooManyRequests,
+			want:   map[string]bool{"/feed.xml": false},
+		},
+		{
+			name: "unreachable",
+			want: map[string]bool{"/feed.xml": false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			if tt.status == 0 {
+				server.Close()
+			} else {
+				defer server.Close()
+			}
+
+			f, err := newFetcher(config.FetchConfig{PerHostInterval: "1ms", RespectRobots: true})
+			if err != nil {
+				t.Fatalf("newFetcher: %v", err)
+			}
+			for path, want := range tt.want {
+				_, err := f.get(context.Background(), server.URL+path, "*/*")
+				if got := !errors.Is(err, errRobotsDisallowed); got != want {
+					t.Errorf("%s allowed = %v (error %v), want %v", path, got, err, want)
+				}
+			}
+			// robots.txt is fetched once and the answer cached, errors included
+			if n := requests.Load(); tt.status != 0 && n != int32(1+countTrue(tt.want)) {
+				t.Errorf("server got %d requests, want robots.txt once and each allowed URL", n)
+			}
+		})
+	}
+}
+
+func countTrue(m map[string]bool) int {
+	n := 0
+	for _, v := range m {
+		if v {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/content"
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+const (
+	// shortIDLength is the number of hex digits of a post ID shown by browse
+	shortIDLength = 8
+	// minIDPrefixLength is the shortest ID prefix accepted when looking up a post
+	minIDPrefixLength = 4
+)
+
+// postRecord is the structured form of a post printed by browse
+type postRecord struct {
+	ID          string     `json:"id"`
+	ShortID     string     `json:"short_id"`
+	FeedID      string     `json:"feed_id"`
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	Author      *string    `json:"author"`
+	Categories  []string   `json:"categories"`
+	PublishedAt *time.Time `json:"published_at"`
+	Description *string    `json:"description"`
+	Content     *string    `json:"content"`
+	Highlighted bool       `json:"highlighted"`
+}
+
+func newPostRecord(post database.Post, highlighted bool) postRecord {
+	return postRecord{
+		ID:          post.ID.String(),
+		ShortID:     shortPostID(post.ID),
+		FeedID:      post.FeedID.String(),
+		Title:       post.Title,
+		Url:         post.Url,
+		Author:      optionalString(post.Author),
+		Categories:  post.Categories,
+		PublishedAt: optionalTime(post.PublishedAt),
+		Description: optionalString(post.Description),
+		Content:     optionalString(post.Content),
+		Highlighted: highlighted,
+	}
+}
+
+// postDetailRecord is the structured form of a post printed by show
+type postDetailRecord struct {
+	postRecord
+	FeedName   string            `json:"feed_name"`
+	FeedUrl    string            `json:"feed_url"`
+	Enclosures []enclosureRecord `json:"enclosures"`
+}
+
+type enclosureRecord struct {
+	Url      string  `json:"url"`
+	MimeType *string `json:"mime_type"`
+	Length   *int64  `json:"length"`
+}
+
+// shortPostID returns the abbreviated form of a post ID printed by browse.
+// It is the start of the post's UUID, so it never changes once a post is stored.
+func shortPostID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
+
+// isIDPrefix reports whether s looks like the start of a UUID
+func isIDPrefix(s string) bool {
+	if len(s) < minIDPrefixLength || len(s) > len(uuid.Nil.String()) {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef-", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolvePost looks up a post by its ID, an unambiguous prefix of its ID as
+// printed by browse, or its URL
+func resolvePost(ctx context.Context, s *state, ref string) (database.GetPostsByIDPrefixRow, error) {
+	prefix := strings.ToLower(ref)
+	if !isIDPrefix(prefix) {
+		post, err := s.db.GetPostByUrl(ctx, ref)
+		if err != nil {
+			return database.GetPostsByIDPrefixRow{}, fmt.Errorf("error getting post by URL: %v", err)
+		}
+		prefix = post.ID.String()
+	}
+
+	posts, err := s.db.GetPostsByIDPrefix(ctx, prefix+"%")
+	if err != nil {
+		return database.GetPostsByIDPrefixRow{}, fmt.Errorf("error getting post: %v", err)
+	}
+	switch len(posts) {
+	case 0:
+		return database.GetPostsByIDPrefixRow{}, fmt.Errorf("no post matches %q", ref)
+	case 1:
+		return posts[0], nil
+	default:
+		return database.GetPostsByIDPrefixRow{}, fmt.Errorf("post ID %q is ambiguous: use more characters", ref)
+	}
+}
+
+func handlerShow(s *state, cmd command, user database.User) error {
+	ctx := context.Background()
+
+	post, err := resolvePost(ctx, s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+	enclosures, err := s.db.GetPostEnclosures(ctx, post.ID)
+	if err != nil {
+		return fmt.Errorf("error getting enclosures: %v", err)
+	}
+
+	record := postDetailRecord{
+		postRecord: newPostRecord(database.Post{
+			ID:          post.ID,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			Author:      post.Author,
+			Categories:  post.Categories,
+		}, false),
+		FeedName:   post.FeedName,
+		FeedUrl:    post.FeedUrl,
+		Enclosures: []enclosureRecord{},
+	}
+	for _, enclosure := range enclosures {
+		var length *int64
+		if enclosure.Length.Valid {
+			length = &enclosure.Length.Int64
+		}
+		record.Enclosures = append(record.Enclosures, enclosureRecord{
+			Url:      enclosure.Url,
+			MimeType: optionalString(enclosure.MimeType),
+			Length:   length,
+		})
+	}
+	err = s.out.emit(record, func() {
+		fmt.Printf("Title: %s\n", post.Title)
+		fmt.Printf("ID: %s\n", post.ID)
+		fmt.Printf("Feed: %s (%s)\n", post.FeedName, post.FeedUrl)
+		if post.Author.Valid {
+			fmt.Printf("Author: %s\n", post.Author.String)
+		}
+		if post.PublishedAt.Valid {
+			fmt.Printf("Published At: %s\n", post.PublishedAt.Time)
+		}
+		if len(post.Categories) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(post.Categories, ", "))
+		}
+		fmt.Printf("URL: %s\n", post.Url)
+		for _, enclosure := range enclosures {
+			details := []string{}
+			if enclosure.MimeType.Valid {
+				details = append(details, enclosure.MimeType.String)
+			}
+			if enclosure.Length.Valid {
+				details = append(details, fmt.Sprintf("%d bytes", enclosure.Length.Int64))
+			}
+			if len(details) > 0 {
+				fmt.Printf("Enclosure: %s (%s)\n", enclosure.Url, strings.Join(details, ", "))
+			} else {
+				fmt.Printf("Enclosure: %s\n", enclosure.Url)
+			}
+		}
+
+		body := post.Content
+		if !body.Valid {
+			body = post.Description
+		}
+		if body.Valid {
+			fmt.Printf("\n%s\n", content.RenderText(body.String, terminalWidth()))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.db.MarkPostRead(ctx, database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error marking post as read: %v", err)
+	}
+	return nil
+}
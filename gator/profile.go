@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/sushiqiren/gator/internal/config"
+)
+
+type profileRecord struct {
+	Name        string `json:"name"`
+	DatabaseURL string `json:"database_url"`
+	User        string `json:"user"`
+	Current     bool   `json:"current"`
+}
+
+// handlerProfile manages the named profiles of the config file. Changes take
+// effect from the next gator command; the shell refuses "profile use" because
+// it keeps the profile it started with.
+func handlerProfile(s *state, cmd command) error {
+	path := s.cfg.File()
+	action := cmd.args[0]
+	if action == "list" {
+		return listProfiles(s, path)
+	}
+
+	if len(cmd.args) < 2 {
+		return fmt.Errorf("missing name argument\nusage: gator profile %s <name>", action)
+	}
+	name := cmd.args[1]
+
+	switch action {
+	case "use":
+		if err := config.UseProfile(path, name); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to profile %s\n", name)
+	case "add":
+		databaseURL := cmd.stringFlag("db-url")
+		if databaseURL == "" {
+			return fmt.Errorf("missing --db-url flag\nusage: gator profile add <name> --db-url <url> [--user <name>]")
+		}
+		if err := checkDatabaseURL(databaseURL); err != nil {
+			return err
+		}
+		profile := config.Profile{DatabaseURL: databaseURL, CurrentUserName: cmd.stringFlag("user")}
+		if err := config.AddProfile(path, name, profile); err != nil {
+			return err
+		}
+		fmt.Printf("Added profile %s. Run \"gator profile use %s\" to switch to it or \"gator --profile %s <command>\" to use it once.\n", name, name, name)
+	case "remove":
+		if err := config.RemoveProfile(path, name); err != nil {
+			return err
+		}
+		fmt.Printf("Removed profile %s\n", name)
+	}
+	return nil
+}
+
+func listProfiles(s *state, path string) error {
+	profiles, err := config.ListProfiles(path)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile := profiles[name]
+		record := profileRecord{
+			Name:        name,
+			DatabaseURL: redactPassword(profile.DatabaseURL),
+			User:        profile.CurrentUserName,
+			Current:     name == s.cfg.ProfileName(),
+		}
+		err := s.out.emit(record, func() {
+			marker := " "
+			if record.Current {
+				marker = "*"
+			}
+			fmt.Printf("%s %-12s %s", marker, name, record.DatabaseURL)
+			if profile.CurrentUserName != "" {
+				fmt.Printf(" (user %s)", profile.CurrentUserName)
+			}
+			fmt.Println()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redactPassword hides the password of a database URL
+func redactPassword(databaseURL string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil || u.User == nil {
+		return databaseURL
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/database"
+	"github.com/sushiqiren/gator/internal/store"
+)
+
+// relocateFeed points a feed at the URL it was permanently redirected to and
+// keeps the old URL as an alias. If another feed already uses the new URL, the
+// follows, posts, filters and aliases of the moved feed are merged into it.
+// It returns the ID of the feed that now owns the new URL.
+func relocateFeed(ctx context.Context, s *state, feedID uuid.UUID, oldURL, newURL string) (uuid.UUID, error) {
+	targetID := feedID
+	err := s.db.InTx(ctx, func(q store.Store) error {
+		existing, err := q.GetFeedByUrl(ctx, newURL)
+		switch {
+		case err == sql.ErrNoRows:
+			// Nobody uses the new URL yet, so simply move the feed
+			err = q.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{ID: feedID, Url: newURL})
+			if err != nil {
+				return fmt.Errorf("error updating feed URL: %v", err)
+			}
+		case err != nil:
+			return fmt.Errorf("error getting feed by URL: %v", err)
+		case existing.ID == feedID:
+			// The feed is moving back to one of its own aliases
+			if err := q.DeleteFeedAlias(ctx, newURL); err != nil {
+				return fmt.Errorf("error deleting feed alias: %v", err)
+			}
+			err = q.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{ID: feedID, Url: newURL})
+			if err != nil {
+				return fmt.Errorf("error updating feed URL: %v", err)
+			}
+		default:
+			// Another feed already lives at the new URL, so merge into it
+			targetID = existing.ID
+			if err := mergeFeeds(ctx, q, feedID, targetID); err != nil {
+				return err
+			}
+		}
+
+		err = q.CreateFeedAlias(ctx, database.CreateFeedAliasParams{
+			Url:       oldURL,
+			FeedID:    targetID,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("error creating feed alias: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return feedID, err
+	}
+	return targetID, nil
+}
+
+// mergeFeeds moves everything that references the source feed onto the target feed
+// and deletes the source feed
+func mergeFeeds(ctx context.Context, q store.Store, fromID, toID uuid.UUID) error {
+	err := q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{ToFeedID: toID, FromFeedID: fromID})
+	if err != nil {
+		return fmt.Errorf("error moving feed follows: %v", err)
+	}
+
+	err = q.MoveFeedPosts(ctx, database.MoveFeedPostsParams{ToFeedID: toID, FromFeedID: fromID})
+	if err != nil {
+		return fmt.Errorf("error moving posts: %v", err)
+	}
+
+	err = q.MoveFeedFilters(ctx, database.MoveFeedFiltersParams{
+		ToFeedID:   uuid.NullUUID{UUID: toID, Valid: true},
+		FromFeedID: uuid.NullUUID{UUID: fromID, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error moving filters: %v", err)
+	}
+
+	err = q.MoveFeedAliases(ctx, database.MoveFeedAliasesParams{ToFeedID: toID, FromFeedID: fromID})
+	if err != nil {
+		return fmt.Errorf("error moving feed aliases: %v", err)
+	}
+
+	if err := q.DeleteFeed(ctx, fromID); err != nil {
+		return fmt.Errorf("error deleting merged feed: %v", err)
+	}
+	return nil
+}
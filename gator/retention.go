@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+// prunePosts deletes posts that fall outside the retention policy and returns
+// how many rows were removed for exceeding the maximum age and the per-feed count
+func prunePosts(ctx context.Context, s *state) (expired int64, excess int64, err error) {
+	retention := s.cfg.Retention
+
+	expired, err = s.db.DeleteExpiredPosts(ctx, int32(retention.MaxAgeDays))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error deleting expired posts: %v", err)
+	}
+
+	excess, err = s.db.DeleteExcessPosts(ctx, int32(retention.MaxPostsPerFeed))
+	if err != nil {
+		return expired, 0, fmt.Errorf("error deleting excess posts: %v", err)
+	}
+
+	return expired, excess, nil
+}
+
+func handlerPrune(s *state, cmd command) error {
+	expired, excess, err := prunePosts(context.Background(), s)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Pruned %d posts (%d older than the maximum age, %d over the per-feed limit)\n", expired+excess, expired, excess)
+	return nil
+}
+
+// parseRetentionLimit parses a per-feed retention override, where "default" clears the override
+func parseRetentionLimit(value string) (sql.NullInt32, error) {
+	if value == "default" {
+		return sql.NullInt32{}, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return sql.NullInt32{}, fmt.Errorf("invalid retention limit %q: expected a non-negative number or \"default\"", value)
+	}
+	return sql.NullInt32{Int32: int32(n), Valid: true}, nil
+}
+
+func handlerFeedRetention(s *state, cmd command) error {
+	feedUrl := cmd.args[0]
+
+	maxAgeDays, err := parseRetentionLimit(cmd.args[1])
+	if err != nil {
+		return err
+	}
+	maxPosts, err := parseRetentionLimit(cmd.args[2])
+	if err != nil {
+		return err
+	}
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), feedUrl)
+	if err != nil {
+		return fmt.Errorf("error getting feed by URL: %v", err)
+	}
+
+	err = s.db.SetFeedRetention(context.Background(), database.SetFeedRetentionParams{
+		ID:                  feed.ID,
+		RetentionMaxAgeDays: maxAgeDays,
+		RetentionMaxPosts:   maxPosts,
+	})
+	if err != nil {
+		return fmt.Errorf("error setting feed retention: %v", err)
+	}
+
+	fmt.Printf("Retention for %s set to max age %s days, max posts %s\n", feedUrl, cmd.args[1], cmd.args[2])
+	return nil
+}
+
+func handlerSave(s *state, cmd command, user database.User) error {
+
+	post, err := resolvePost(context.Background(), s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SavePost(context.Background(), database.SavePostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("error saving post: %v", err)
+	}
+
+	fmt.Printf("Saved post: %s\n", post.Title)
+	return nil
+}
+
+func handlerUnsave(s *state, cmd command, user database.User) error {
+
+	post, err := resolvePost(context.Background(), s, cmd.args[0])
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.db.UnsavePost(context.Background(), database.UnsavePostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("error unsaving post: %v", err)
+	}
+	if removed == 0 {
+		return fmt.Errorf("post %s is not saved", post.Url)
+	}
+
+	fmt.Printf("Unsaved post: %s\n", post.Title)
+	return nil
+}
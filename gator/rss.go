@@ -1,25 +1,93 @@
 package main
 
 import (
-    "encoding/xml"
-    
+	"encoding/xml"
+	"slices"
+	"strings"
 )
 
 type RSSFeed struct {
-    XMLName     xml.Name    `xml:"rss"`
-    Channel     RSSChannel  `xml:"channel"`
+	XMLName xml.Name   `xml:"rss"`
+	Channel RSSChannel `xml:"channel"`
 }
 
+// RSSChannel lists ITunesImage before Image on purpose: the decoder gives an
+// element to the first field that matches it, and a tag without a namespace
+// matches <itunes:image> too
 type RSSChannel struct {
-    Title       string      `xml:"title"`
-    Description string      `xml:"description"`
-    Link        string      `xml:"link"`
-    Items       []RSSItem   `xml:"item"`
+	Title       string      `xml:"title"`
+	Description string      `xml:"description"`
+	Links       []string    `xml:"link"`
+	Language    string      `xml:"language"`
+	Generator   string      `xml:"generator"`
+	ITunesImage ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	Image       RSSImage    `xml:"image"`
+	Items       []RSSItem   `xml:"item"`
+}
+
+type RSSImage struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+}
+
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
 }
 
 type RSSItem struct {
-    Title       string      `xml:"title"`
-    Description string      `xml:"description"`
-    Link        string      `xml:"link"`
-    PubDate     string      `xml:"pubDate"`
-}
\ No newline at end of file
+	Title       string         `xml:"title"`
+	Description string         `xml:"description"`
+	Link        string         `xml:"link"`
+	PubDate     string         `xml:"pubDate"`
+	Author      string         `xml:"author"`
+	Creator     string         `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string       `xml:"category"`
+	Enclosures  []RSSEnclosure `xml:"enclosure"`
+}
+
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// Link returns the channel's site link. Feeds often also carry an empty
+// <atom:link rel="self"> element, which shares the local name "link".
+func (c RSSChannel) Link() string {
+	for _, link := range c.Links {
+		if link = strings.TrimSpace(link); link != "" {
+			return link
+		}
+	}
+	return ""
+}
+
+// ImageURL returns the channel's image, falling back to the iTunes artwork
+func (c RSSChannel) ImageURL() string {
+	if url := strings.TrimSpace(c.Image.URL); url != "" {
+		return url
+	}
+	return strings.TrimSpace(c.ITunesImage.Href)
+}
+
+// AuthorName returns the item's author, preferring the RSS <author> element
+// over Dublin Core's <dc:creator>
+func (i RSSItem) AuthorName() string {
+	if author := strings.TrimSpace(i.Author); author != "" {
+		return author
+	}
+	return strings.TrimSpace(i.Creator)
+}
+
+// CategoryNames returns the item's non-empty categories without duplicates
+func (i RSSItem) CategoryNames() []string {
+	names := make([]string, 0, len(i.Categories))
+	for _, category := range i.Categories {
+		category = strings.TrimSpace(category)
+		if category != "" && !slices.Contains(names, category) {
+			names = append(names, category)
+		}
+	}
+	return names
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestChannelImageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "rss image",
+			body: `<rss><channel><image><url>http://x/rss.png</url></image></channel></rss>`,
+			want: "http://x/rss.png",
+		},
+		{
+			name: "itunes image only",
+			body: `<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>
+				<itunes:image href="http://x/itunes.png"/>
+			</channel></rss>`,
+			want: "http://x/itunes.png",
+		},
+		{
+			name: "both prefer rss image",
+			body: `<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>
+				<itunes:image href="http://x/itunes.png"/>
+				<image><url>http://x/rss.png</url></image>
+			</channel></rss>`,
+			want: "http://x/rss.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, _, err := parseFeed([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("parseFeed: %v", err)
+			}
+			if got := feed.Channel.ImageURL(); got != tt.want {
+				t.Errorf("ImageURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
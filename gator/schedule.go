@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/sushiqiren/gator/internal/database"
+)
+
+const (
+	// defaultTooManyRequestsBackoff is used when a 429 response has no Retry-After header
+	defaultTooManyRequestsBackoff = time.Hour
+	// maxRetryAfter caps how far a server can push back the next fetch
+	maxRetryAfter = 7 * 24 * time.Hour
+)
+
+// handleFetchError records the scheduling consequences of a failed fetch:
+// 410 Gone marks the feed as dead, while 429 and 503 defer the next fetch
+// according to the server's Retry-After header
+func handleFetchError(ctx context.Context, s *state, feed database.Feed, err error) {
+	var statusErr *fetchStatusError
+	if !errors.As(err, &statusErr) {
+		return
+	}
+
+	switch statusErr.StatusCode {
+	case http.StatusGone:
+		if err := s.db.MarkFeedDead(ctx, feed.ID); err != nil {
+			log.Printf("error marking feed as dead: %v", err)
+			return
+		}
+		log.Printf("feed %s is gone, it will no longer be fetched", feed.Url)
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		delay := statusErr.RetryAfter
+		if delay == 0 && statusErr.StatusCode == http.StatusTooManyRequests {
+			delay = defaultTooManyRequestsBackoff
+		}
+		if delay == 0 {
+			return
+		}
+		if delay > maxRetryAfter {
+			delay = maxRetryAfter
+		}
+
+		// The database adds the delay to its own clock, which is the one
+		// GetNextFeedsToFetch compares next_fetch_at with
+		err := s.db.DeferFeed(ctx, database.DeferFeedParams{
+			ID:           feed.ID,
+			DelaySeconds: delay.Seconds(),
+		})
+		if err != nil {
+			log.Printf("error deferring feed: %v", err)
+			return
+		}
+		log.Printf("feed %s asked us to back off, next fetch at %s", feed.Url, time.Now().Add(delay).Format(time.RFC1123))
+	}
+}
+
+func handlerReviveFeed(s *state, cmd command) error {
+	feedUrl := cmd.args[0]
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), feedUrl)
+	if err != nil {
+		return fmt.Errorf("error getting feed by URL: %v", err)
+	}
+
+	if err := s.db.ReviveFeed(context.Background(), feed.ID); err != nil {
+		return fmt.Errorf("error reviving feed: %v", err)
+	}
+
+	fmt.Printf("Feed %s will be fetched again\n", feed.Url)
+	return nil
+}
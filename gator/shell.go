@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sushiqiren/gator/internal/config"
+	"golang.org/x/term"
+)
+
+// handlerShell returns the shell command, which reads commands interactively
+// and runs them against a single long-lived state
+func handlerShell(cmds *commands) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+		if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+			return fmt.Errorf("shell command must be run in a terminal")
+		}
+
+		terminal := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{stdin, os.Stdout}, "")
+		terminal.AutoCompleteCallback = shellCompleter(s, cmds)
+
+		fmt.Println("Type \"help\" for a list of commands and \"exit\" or Ctrl-D to leave.")
+		for {
+			if width, height, err := term.GetSize(outFd); err == nil && width > 0 {
+				terminal.SetSize(width, height)
+			}
+			terminal.SetPrompt(shellPrompt(s))
+
+			// Line editing needs raw mode, commands run with the terminal restored
+			oldState, err := term.MakeRaw(inFd)
+			if err != nil {
+				return fmt.Errorf("error switching terminal to raw mode: %v", err)
+			}
+			line, err := terminal.ReadLine()
+			term.Restore(inFd, oldState)
+			if errors.Is(err, io.EOF) {
+				fmt.Println()
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("error reading command: %v", err)
+			}
+
+			args, err := splitArgs(line)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				continue
+			}
+			if len(args) == 0 {
+				continue
+			}
+			switch args[0] {
+			case "exit", "quit":
+				return nil
+			case "shell":
+				fmt.Fprintf(os.Stderr, "Error: already in the shell\n")
+				continue
+			case "profile":
+				// The shell keeps the config and database it started with
+				if len(args) > 1 && args[1] == "use" {
+					fmt.Fprintf(os.Stderr, "Error: cannot switch profiles in the shell: exit and run \"gator profile use\", or start the shell with --profile\n")
+					continue
+				}
+			}
+
+			if err := cmds.run(s, command{name: args[0], args: args[1:]}); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// shellPrompt shows the logged-in user, and the profile unless it is the
+// default one
+func shellPrompt(s *state) string {
+	who := s.cfg.CurrentUserName
+	if profile := s.cfg.ProfileName(); profile != config.DefaultProfile {
+		who = profile + ":" + who
+	}
+	if who == "" {
+		return "gator> "
+	}
+	return fmt.Sprintf("gator (%s)> ", who)
+}
+
+// shellCompleter completes the word before the cursor when Tab is pressed,
+// using the same candidates as shell completion. With several candidates the
+// word is extended to their longest common prefix.
+func shellCompleter(s *state, cmds *commands) func(line string, pos int, key rune) (string, int, bool) {
+	return func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		prefix := line[:pos]
+		candidates := cmds.complete(s, "gator "+prefix)
+		if len(candidates) == 0 {
+			return "", 0, false
+		}
+
+		start := strings.LastIndexAny(prefix, " \t") + 1
+		insert := candidates[0]
+		for _, candidate := range candidates[1:] {
+			for !strings.HasPrefix(candidate, insert) {
+				insert = insert[:len(insert)-1]
+			}
+		}
+		if len(candidates) == 1 {
+			insert += " "
+		}
+		if len(insert) <= pos-start {
+			return "", 0, false
+		}
+		return prefix[:start] + insert + line[pos:], start + len(insert), true
+	}
+}
+
+// splitArgs splits a command line into arguments the way a POSIX shell does
+// for simple commands, honoring single quotes, double quotes and backslashes
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
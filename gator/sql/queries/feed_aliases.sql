@@ -0,0 +1,13 @@
+-- name: CreateFeedAlias :exec
+INSERT INTO feed_aliases (url, feed_id, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (url) DO UPDATE SET feed_id = EXCLUDED.feed_id;
+
+-- name: DeleteFeedAlias :exec
+DELETE FROM feed_aliases
+WHERE url = $1;
+
+-- name: MoveFeedAliases :exec
+UPDATE feed_aliases
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
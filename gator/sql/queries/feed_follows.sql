@@ -15,12 +15,14 @@ INNER JOIN users ON users.id = inserted_feed_follow.user_id;
 -- name: GetFeedByUrl :one
 SELECT id, created_at, updated_at, name, url, user_id
 FROM feeds
-WHERE url = $1;
+WHERE url = $1
+OR id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1);
 
 -- name: GetFeedFollowsForUser :many
 SELECT
     feed_follows.*,
     feeds.name AS feed_name,
+    feeds.url AS feed_url,
     users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
@@ -32,4 +34,10 @@ DELETE FROM feed_follows
 USING feeds
 WHERE feed_follows.feed_id = feeds.id
 AND feed_follows.user_id = $1
-AND feeds.url = $2;
\ No newline at end of file
+AND (feeds.url = $2 OR feeds.id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $2));
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+SELECT gen_random_uuid(), feed_follows.created_at, NOW(), feed_follows.user_id, sqlc.arg(to_feed_id)::uuid
+FROM feed_follows
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
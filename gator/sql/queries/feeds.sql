@@ -4,7 +4,7 @@ VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, created_at, updated_at, name, url, user_id;
 
 -- name: GetFeedsWithUserNames :many
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name AS feed_name, feeds.url, users.name AS user_name
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name AS feed_name, feeds.url, feeds.site_title, feeds.site_link, users.name AS user_name
 FROM feeds
 JOIN users ON feeds.user_id = users.id;
 
@@ -13,8 +13,61 @@ UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+-- name: GetNextFeedsToFetch :many
+SELECT *
 FROM feeds
+WHERE dead_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
 ORDER BY last_fetched_at NULLS FIRST, updated_at
-LIMIT 1;
\ No newline at end of file
+LIMIT $1;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_days = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET site_title = $2, site_description = $3, site_link = $4, language = $5, image_url = $6, generator = $7, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetFeedInfoByUrl :one
+SELECT feeds.*, users.name AS user_name
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.url = $1
+OR feeds.id = (SELECT feed_id FROM feed_aliases WHERE feed_aliases.url = $1);
+
+-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET url = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ReviveFeed :exec
+UPDATE feeds
+SET dead_at = NULL, next_fetch_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeferFeed :exec
+UPDATE feeds
+SET next_fetch_at = NOW() + make_interval(secs => sqlc.arg(delay_seconds)::float8), updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: SetFeedParseWarning :exec
+UPDATE feeds
+SET parse_warning = $2
+WHERE id = $1;
+
+-- name: SetFeedFetchFullContent :exec
+UPDATE feeds
+SET fetch_full_content = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,8 @@
+-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (id, post_id, url, mime_type, length)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetPostEnclosures :many
+SELECT id, post_id, url, mime_type, length
+FROM post_enclosures
+WHERE post_id = $1;
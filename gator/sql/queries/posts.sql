@@ -1,18 +1,105 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id;
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, categories)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories;
 
 -- name: GetPostsByFeedID :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories
 FROM posts
 WHERE feed_id = $1;
 
 -- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.author, posts.categories
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feeds.id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+ORDER BY posts.published_at DESC, posts.id
+LIMIT $2 OFFSET $3;
+
+-- name: GetPostByUrl :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, author, categories
+FROM posts
+WHERE url = $1;
+
+-- name: DeleteExpiredPosts :execrows
+DELETE FROM posts
+USING feeds
+WHERE posts.feed_id = feeds.id
+AND COALESCE(feeds.retention_max_age_days, sqlc.arg(default_max_age_days)::int) > 0
+AND COALESCE(posts.published_at, posts.created_at) < NOW() - make_interval(days => COALESCE(feeds.retention_max_age_days, sqlc.arg(default_max_age_days)::int))
+AND NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id);
+
+-- name: DeleteExcessPosts :execrows
+DELETE FROM posts
+WHERE posts.id IN (
+    SELECT ranked.id
+    FROM (
+        SELECT
+            posts.id,
+            ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC) AS position,
+            COALESCE(feeds.retention_max_posts, sqlc.arg(default_max_posts)::int) AS max_posts
+        FROM posts
+        JOIN feeds ON posts.feed_id = feeds.id
+        WHERE NOT EXISTS (SELECT 1 FROM saved_posts WHERE saved_posts.post_id = posts.id)
+    ) AS ranked
+    WHERE ranked.max_posts > 0 AND ranked.position > ranked.max_posts
+);
+
+-- name: MoveFeedPosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetPostsByIDPrefix :many
+SELECT posts.*, feeds.name AS feed_name, feeds.url AS feed_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+WHERE posts.id::text LIKE sqlc.arg(id_prefix)
+ORDER BY posts.id
+LIMIT 2;
+
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id, read_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :execrows
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetPostListForUser :many
+SELECT
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.author, posts.categories,
+    feeds.name AS feed_name,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM saved_posts
+        WHERE saved_posts.post_id = posts.id AND saved_posts.user_id = feed_follows.user_id
+    ) AS is_saved
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+ORDER BY posts.published_at DESC, posts.id
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, COUNT(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+)
+GROUP BY posts.feed_id;
@@ -0,0 +1,21 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (token_hash, user_id, created_at, expires_at)
+VALUES ($1, $2, NOW(), NOW() + make_interval(secs => sqlc.arg(lifetime_seconds)::float8));
+
+-- name: GetSession :one
+SELECT token_hash, user_id, created_at, expires_at
+FROM sessions
+WHERE token_hash = $1
+AND expires_at > NOW();
+
+-- name: DeleteSession :exec
+DELETE FROM sessions
+WHERE token_hash = $1;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions
+WHERE expires_at <= NOW();
@@ -0,0 +1,20 @@
+-- name: CreateUserFilter :one
+INSERT INTO user_filters (id, created_at, updated_at, user_id, action, field, pattern, is_regex, feed_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetUserFiltersForUser :many
+SELECT user_filters.*, feeds.url AS feed_url
+FROM user_filters
+LEFT JOIN feeds ON feeds.id = user_filters.feed_id
+WHERE user_filters.user_id = $1
+ORDER BY user_filters.created_at;
+
+-- name: DeleteUserFilter :execrows
+DELETE FROM user_filters
+WHERE id = $1 AND user_id = $2;
+
+-- name: MoveFeedFilters :exec
+UPDATE user_filters
+SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_feed_id);
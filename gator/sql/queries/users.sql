@@ -1,15 +1,16 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, password_hash)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    $5
 )
 RETURNING *;
 
 -- name: GetUserByName :one
-SELECT id, created_at, updated_at, name
+SELECT id, created_at, updated_at, name, password_hash
 FROM users
 WHERE name = $1;
 
@@ -17,5 +18,10 @@ WHERE name = $1;
 DELETE FROM users;
 
 -- name: GetUsers :many
-SELECT id, created_at, updated_at, name
-FROM users;
\ No newline at end of file
+SELECT id, created_at, updated_at, name, password_hash
+FROM users;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, updated_at = $3
+WHERE id = $1;
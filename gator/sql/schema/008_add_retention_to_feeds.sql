@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN retention_max_age_days INTEGER NULL,
+ADD COLUMN retention_max_posts INTEGER NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN retention_max_age_days,
+DROP COLUMN retention_max_posts;
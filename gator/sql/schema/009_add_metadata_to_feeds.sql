@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN site_title TEXT NULL,
+ADD COLUMN site_description TEXT NULL,
+ADD COLUMN site_link TEXT NULL,
+ADD COLUMN language TEXT NULL,
+ADD COLUMN image_url TEXT NULL,
+ADD COLUMN generator TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN site_title,
+DROP COLUMN site_description,
+DROP COLUMN site_link,
+DROP COLUMN language,
+DROP COLUMN image_url,
+DROP COLUMN generator;
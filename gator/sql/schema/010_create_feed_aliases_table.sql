@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE feed_aliases (
+    url VARCHAR(255) PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_aliases;
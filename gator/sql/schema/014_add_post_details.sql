@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT NULL,
+ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE post_enclosures (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT NULL,
+    length BIGINT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE post_reads (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    read_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_reads;
+DROP TABLE post_enclosures;
+
+ALTER TABLE posts
+DROP COLUMN author,
+DROP COLUMN categories;
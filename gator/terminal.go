@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+var (
+	stdinOnce   sync.Once
+	stdinChunks chan []byte
+)
+
+// readStdin returns a channel carrying raw input from the terminal. A single
+// goroutine reads stdin for the life of the process, so input is never lost
+// to a reader left behind by a previous interactive view.
+func readStdin() <-chan []byte {
+	stdinOnce.Do(func() {
+		stdinChunks = make(chan []byte)
+		go func() {
+			defer close(stdinChunks)
+			for {
+				buf := make([]byte, 256)
+				n, err := os.Stdin.Read(buf)
+				if n > 0 {
+					stdinChunks <- buf[:n]
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+	})
+	return stdinChunks
+}
+
+// stdinReader is an io.Reader over the shared stdin channel. Interactive code
+// reads input through it instead of os.Stdin.
+type stdinReader struct {
+	pending []byte
+}
+
+var stdin = &stdinReader{}
+
+func (r *stdinReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		chunk, ok := <-readStdin()
+		if !ok {
+			return 0, io.EOF
+		}
+		r.pending = chunk
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// promptLine asks a question on stdout and reads one line of the answer from
+// the shared stdin. An empty answer returns def.
+func promptLine(prompt, def string) (string, error) {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", prompt, def)
+	} else {
+		fmt.Printf("%s: ", prompt)
+	}
+
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		_, err := stdin.Read(buf)
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[0])
+	}
+
+	answer := strings.TrimSpace(string(line))
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// promptPassword asks for a password without echoing it. When stdin is not a
+// terminal the password is read as a plain line, so scripts can pipe it in.
+func promptPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return promptLine(prompt, "")
+	}
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", fmt.Errorf("error switching terminal to raw mode: %v", err)
+	}
+	defer term.Restore(fd, oldState)
+
+	terminal := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{stdin, os.Stdout}, "")
+	return terminal.ReadPassword(prompt + ": ")
+}
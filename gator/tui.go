@@ -0,0 +1,619 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/sushiqiren/gator/internal/content"
+	"github.com/sushiqiren/gator/internal/database"
+	"golang.org/x/term"
+)
+
+const (
+	// tuiRefreshInterval is how often the TUI reloads feeds and posts, so posts
+	// fetched by an agg running elsewhere show up without restarting
+	tuiRefreshInterval = time.Minute
+	// tuiPostLimit caps the number of posts listed for the selected feed
+	tuiPostLimit = 500
+)
+
+const tuiHelp = "q quit  tab pane  j/k move  enter read  m read/unread  s save  o open  r refresh"
+
+// ANSI escape sequences used to draw the TUI
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiBold       = "\x1b[1m"
+	ansiReverse    = "\x1b[7m"
+	ansiReset      = "\x1b[0m"
+)
+
+type tuiPane int
+
+const (
+	paneFeeds tuiPane = iota
+	panePosts
+	paneReader
+)
+
+// tuiFeed is an entry in the feed pane. The first entry has no ID and lists
+// the posts of every followed feed.
+type tuiFeed struct {
+	id     uuid.NullUUID
+	name   string
+	unread int64
+}
+
+type tui struct {
+	ctx           context.Context
+	s             *state
+	user          database.User
+	out           *bufio.Writer
+	width, height int
+
+	filters     postFilters
+	feeds       []tuiFeed
+	feedIdx     int
+	feedTop     int
+	posts       []database.GetPostListForUserRow
+	highlighted map[uuid.UUID]bool
+	postIdx     int
+	postTop     int
+
+	// reading is the post shown in the reading pane, if any
+	reading   *database.GetPostListForUserRow
+	readerTop int
+
+	focus  tuiPane
+	status string
+}
+
+func handlerTUI(s *state, cmd command, user database.User) error {
+	inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return fmt.Errorf("tui command must be run in a terminal")
+	}
+
+	filters, err := loadPostFilters(context.Background(), s, user.ID)
+	if err != nil {
+		return err
+	}
+
+	oldState, err := term.MakeRaw(inFd)
+	if err != nil {
+		return fmt.Errorf("error switching terminal to raw mode: %v", err)
+	}
+	defer term.Restore(inFd, oldState)
+
+	t := &tui{
+		ctx:     context.Background(),
+		s:       s,
+		user:    user,
+		out:     bufio.NewWriter(os.Stdout),
+		filters: filters,
+	}
+	t.out.WriteString(ansiAltScreen + ansiHideCursor)
+	defer func() {
+		t.out.WriteString(ansiReset + ansiShowCursor + ansiMainScreen)
+		t.out.Flush()
+	}()
+
+	t.resize()
+	t.reload()
+	t.draw()
+
+	keys := readStdin()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastRefresh := time.Now()
+	for {
+		select {
+		case chunk, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range parseKeys(chunk) {
+				if quit := t.handleKey(key); quit {
+					return nil
+				}
+			}
+		case <-ticker.C:
+			// Poll for resizes, which works the same on every platform
+			resized := t.resize()
+			if time.Since(lastRefresh) >= tuiRefreshInterval {
+				t.reload()
+				lastRefresh = time.Now()
+			} else if !resized {
+				continue
+			}
+		}
+		t.resize()
+		t.draw()
+	}
+}
+
+// parseKeys splits raw terminal input into key names. Printable characters
+// are returned as themselves.
+func parseKeys(chunk []byte) []string {
+	sequences := map[string]string{
+		"\x1b[A": "up", "\x1b[B": "down", "\x1b[C": "right", "\x1b[D": "left",
+		"\x1bOA": "up", "\x1bOB": "down", "\x1bOC": "right", "\x1bOD": "left",
+		"\x1b[H": "home", "\x1b[F": "end", "\x1b[1~": "home", "\x1b[4~": "end",
+		"\x1b[5~": "pgup", "\x1b[6~": "pgdn", "\x1b[Z": "backtab",
+	}
+
+	var keys []string
+	s := string(chunk)
+	for len(s) > 0 {
+		if s[0] == 0x1b {
+			matched := false
+			for seq, name := range sequences {
+				if strings.HasPrefix(s, seq) {
+					keys = append(keys, name)
+					s = s[len(seq):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				keys = append(keys, "esc")
+				s = s[1:]
+			}
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		default:
+			if r >= 0x20 && r != 0x7f {
+				keys = append(keys, string(r))
+			}
+		}
+	}
+	return keys
+}
+
+// handleKey applies a key press and reports whether the TUI should exit
+func (t *tui) handleKey(key string) bool {
+	t.status = ""
+	switch key {
+	case "q", "ctrl+c":
+		return true
+	case "tab", "right", "l":
+		if t.focus < paneReader {
+			t.focus++
+		}
+		if t.focus == paneReader && t.reading == nil {
+			t.read()
+		}
+	case "backtab", "left", "h", "esc":
+		if t.focus > paneFeeds {
+			t.focus--
+		}
+	case "down", "j":
+		t.move(1)
+	case "up", "k":
+		t.move(-1)
+	case "pgdn", " ":
+		t.move(t.paneHeight())
+	case "pgup", "b":
+		t.move(-t.paneHeight())
+	case "home", "g":
+		t.move(-1 << 30)
+	case "end", "G":
+		t.move(1 << 30)
+	case "enter":
+		switch t.focus {
+		case paneFeeds:
+			t.focus = panePosts
+		case panePosts:
+			t.read()
+			t.focus = paneReader
+		}
+	case "m":
+		if post := t.currentPost(); post != nil {
+			t.setRead(post, !post.IsRead)
+		}
+	case "s":
+		t.toggleSaved()
+	case "o":
+		if post := t.currentPost(); post != nil {
+			if err := openURL(t.s, post.Url); err != nil {
+				t.status = err.Error()
+			} else {
+				t.setRead(post, true)
+				t.status = "Opened " + post.Url
+			}
+		}
+	case "r":
+		t.reload()
+		t.status = "Refreshed"
+	}
+	return false
+}
+
+// move moves the selection of the focused pane, or scrolls the reading pane
+func (t *tui) move(delta int) {
+	switch t.focus {
+	case paneFeeds:
+		idx := clamp(t.feedIdx+delta, 0, len(t.feeds)-1)
+		if idx != t.feedIdx {
+			t.feedIdx = idx
+			t.postIdx, t.postTop = 0, 0
+			t.loadPosts()
+		}
+	case panePosts:
+		t.postIdx = clamp(t.postIdx+delta, 0, len(t.posts)-1)
+	case paneReader:
+		t.readerTop = clamp(t.readerTop+delta, 0, len(t.readerLines())-t.readerHeight())
+	}
+}
+
+// currentPost returns the post the actions apply to: the one being read when
+// the reading pane has focus, otherwise the selected one
+func (t *tui) currentPost() *database.GetPostListForUserRow {
+	if t.focus == paneReader && t.reading != nil {
+		for i := range t.posts {
+			if t.posts[i].ID == t.reading.ID {
+				return &t.posts[i]
+			}
+		}
+		return t.reading
+	}
+	if t.postIdx < len(t.posts) {
+		return &t.posts[t.postIdx]
+	}
+	return nil
+}
+
+// read shows the selected post in the reading pane and marks it as read
+func (t *tui) read() {
+	if t.postIdx >= len(t.posts) {
+		return
+	}
+	post := t.posts[t.postIdx]
+	t.reading = &post
+	t.readerTop = 0
+	if !post.IsRead {
+		t.setRead(&t.posts[t.postIdx], true)
+	}
+}
+
+func (t *tui) setRead(post *database.GetPostListForUserRow, read bool) {
+	var err error
+	if read {
+		err = t.s.db.MarkPostRead(t.ctx, database.MarkPostReadParams{
+			UserID: t.user.ID,
+			PostID: post.ID,
+			ReadAt: time.Now(),
+		})
+	} else {
+		_, err = t.s.db.MarkPostUnread(t.ctx, database.MarkPostUnreadParams{
+			UserID: t.user.ID,
+			PostID: post.ID,
+		})
+	}
+	if err != nil {
+		t.status = fmt.Sprintf("error updating read status: %v", err)
+		return
+	}
+	if post.IsRead == read {
+		return
+	}
+	post.IsRead = read
+
+	// Keep the unread counts in the feed pane in step without a reload
+	change := int64(1)
+	if read {
+		change = -1
+	}
+	for i := range t.feeds {
+		if !t.feeds[i].id.Valid || t.feeds[i].id.UUID == post.FeedID {
+			t.feeds[i].unread += change
+		}
+	}
+}
+
+func (t *tui) toggleSaved() {
+	post := t.currentPost()
+	if post == nil {
+		return
+	}
+	if post.IsSaved {
+		if _, err := t.s.db.UnsavePost(t.ctx, database.UnsavePostParams{UserID: t.user.ID, PostID: post.ID}); err != nil {
+			t.status = fmt.Sprintf("error unsaving post: %v", err)
+			return
+		}
+		post.IsSaved = false
+		t.status = "Unsaved " + post.Title
+		return
+	}
+	if err := t.s.db.SavePost(t.ctx, database.SavePostParams{UserID: t.user.ID, PostID: post.ID, CreatedAt: time.Now()}); err != nil {
+		t.status = fmt.Sprintf("error saving post: %v", err)
+		return
+	}
+	post.IsSaved = true
+	t.status = "Saved " + post.Title
+}
+
+// reload refreshes the feed pane and the post list, keeping the selections
+func (t *tui) reload() {
+	follows, err := t.s.db.GetFeedFollowsForUser(t.ctx, t.user.ID)
+	if err != nil {
+		t.status = fmt.Sprintf("error getting feeds: %v", err)
+		return
+	}
+	counts, err := t.s.db.GetUnreadCountsForUser(t.ctx, t.user.ID)
+	if err != nil {
+		t.status = fmt.Sprintf("error getting unread counts: %v", err)
+		return
+	}
+	unread := make(map[uuid.UUID]int64, len(counts))
+	total := int64(0)
+	for _, count := range counts {
+		unread[count.FeedID] = count.Unread
+		total += count.Unread
+	}
+
+	var selected uuid.NullUUID
+	if t.feedIdx < len(t.feeds) {
+		selected = t.feeds[t.feedIdx].id
+	}
+	t.feeds = []tuiFeed{{name: "All feeds", unread: total}}
+	sort.Slice(follows, func(i, j int) bool {
+		return strings.ToLower(follows[i].FeedName) < strings.ToLower(follows[j].FeedName)
+	})
+	t.feedIdx = 0
+	for _, follow := range follows {
+		if selected.Valid && selected.UUID == follow.FeedID {
+			t.feedIdx = len(t.feeds)
+		}
+		t.feeds = append(t.feeds, tuiFeed{
+			id:     uuid.NullUUID{UUID: follow.FeedID, Valid: true},
+			name:   follow.FeedName,
+			unread: unread[follow.FeedID],
+		})
+	}
+	t.loadPosts()
+}
+
+// loadPosts lists the posts of the selected feed, applying the user's filters
+func (t *tui) loadPosts() {
+	var selected uuid.UUID
+	if t.postIdx < len(t.posts) {
+		selected = t.posts[t.postIdx].ID
+	}
+
+	rows, err := t.s.db.GetPostListForUser(t.ctx, database.GetPostListForUserParams{
+		UserID:   t.user.ID,
+		FeedID:   t.feeds[t.feedIdx].id,
+		RowLimit: tuiPostLimit,
+	})
+	if err != nil {
+		t.status = fmt.Sprintf("error getting posts: %v", err)
+		return
+	}
+
+	t.posts = t.posts[:0]
+	t.highlighted = make(map[uuid.UUID]bool)
+	t.postIdx = 0
+	for _, row := range rows {
+		hidden, highlighted := t.filters.apply(database.Post{
+			ID:          row.ID,
+			Title:       row.Title,
+			Url:         row.Url,
+			Description: row.Description,
+			FeedID:      row.FeedID,
+			Content:     row.Content,
+		})
+		if hidden {
+			continue
+		}
+		if row.ID == selected {
+			t.postIdx = len(t.posts)
+		}
+		t.highlighted[row.ID] = highlighted
+		t.posts = append(t.posts, row)
+	}
+}
+
+// resize reads the terminal size and reports whether it changed
+func (t *tui) resize() bool {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || (width == t.width && height == t.height) {
+		return false
+	}
+	t.width, t.height = width, height
+	return true
+}
+
+// Layout: the feed pane on the left, the post list above the reading pane on
+// the right and a status bar on the last line
+func (t *tui) feedWidth() int    { return clamp(t.width/4, 16, 40) }
+func (t *tui) rightWidth() int   { return t.width - t.feedWidth() - 1 }
+func (t *tui) listHeight() int   { return max((t.height-1)/3, 3) }
+func (t *tui) readerHeight() int { return t.height - 1 - t.listHeight() - 1 }
+
+// paneHeight is the number of rows visible in the focused pane
+func (t *tui) paneHeight() int {
+	switch t.focus {
+	case paneFeeds:
+		return t.height - 1
+	case panePosts:
+		return t.listHeight()
+	default:
+		return t.readerHeight()
+	}
+}
+
+func (t *tui) readerLines() []string {
+	if t.reading == nil {
+		return nil
+	}
+	post := t.reading
+	width := t.rightWidth() - 2
+
+	lines := []string{post.Title, "Feed: " + post.FeedName}
+	if post.Author.Valid {
+		lines = append(lines, "Author: "+post.Author.String)
+	}
+	if post.PublishedAt.Valid {
+		lines = append(lines, "Published At: "+post.PublishedAt.Time.Format(time.RFC1123))
+	}
+	lines = append(lines, "URL: "+post.Url, "")
+
+	body := post.Content
+	if !body.Valid {
+		body = post.Description
+	}
+	if body.Valid {
+		lines = append(lines, strings.Split(content.RenderText(body.String, width), "\n")...)
+	}
+	return lines
+}
+
+func (t *tui) draw() {
+	t.out.WriteString(ansiHome)
+	if t.width < 40 || t.height < 10 {
+		t.out.WriteString("Terminal too small" + ansiClearLine)
+		t.out.Flush()
+		return
+	}
+
+	feedW, rightW := t.feedWidth(), t.rightWidth()
+	listH, readerH := t.listHeight(), t.readerHeight()
+
+	t.feedTop = scrollTo(t.feedTop, t.feedIdx, t.height-1)
+	t.postTop = scrollTo(t.postTop, t.postIdx, listH)
+	reader := t.readerLines()
+	t.readerTop = clamp(t.readerTop, 0, len(reader)-readerH)
+
+	for row := 0; row < t.height-1; row++ {
+		// Feed pane
+		if i := t.feedTop + row; i < len(t.feeds) {
+			feed := t.feeds[i]
+			label := feed.name
+			if feed.unread > 0 {
+				label = fmt.Sprintf("%s (%d)", feed.name, feed.unread)
+			}
+			t.out.WriteString(t.styled(fit(" "+label, feedW), i == t.feedIdx, paneFeeds, feed.unread > 0))
+		} else {
+			t.out.WriteString(strings.Repeat(" ", feedW))
+		}
+		t.out.WriteString("│")
+
+		// Post list, divider and reading pane
+		switch {
+		case row < listH:
+			if i := t.postTop + row; i < len(t.posts) {
+				post := t.posts[i]
+				flags := []rune("   ")
+				if !post.IsRead {
+					flags[0] = '*'
+				}
+				if post.IsSaved {
+					flags[1] = 's'
+				}
+				date := "          "
+				if post.PublishedAt.Valid {
+					date = post.PublishedAt.Time.Format("2006-01-02")
+				}
+				line := fit(string(flags)+date+"  "+post.Title, rightW)
+				t.out.WriteString(t.styled(line, i == t.postIdx, panePosts, !post.IsRead || t.highlighted[post.ID]))
+			} else if row == 0 && len(t.posts) == 0 {
+				t.out.WriteString(fit(" No posts", rightW))
+			}
+		case row == listH:
+			divider := strings.Repeat("─", rightW)
+			if t.focus == paneReader {
+				divider = ansiBold + divider + ansiReset
+			}
+			t.out.WriteString(divider)
+		default:
+			if i := t.readerTop + row - listH - 1; i < len(reader) {
+				line := fit(" "+reader[i], rightW)
+				if i == 0 {
+					line = ansiBold + line
+				}
+				t.out.WriteString(line)
+			}
+		}
+		t.out.WriteString(ansiReset + ansiClearLine + "\r\n")
+	}
+
+	status := t.status
+	if status == "" {
+		status = tuiHelp
+	}
+	t.out.WriteString(ansiReverse + fit(" "+status, t.width) + ansiReset)
+	t.out.Flush()
+}
+
+// styled highlights the selected row, in reverse video when its pane has focus
+func (t *tui) styled(line string, selected bool, pane tuiPane, bold bool) string {
+	style := ""
+	if bold {
+		style += ansiBold
+	}
+	if selected && t.focus == pane {
+		style += ansiReverse
+	} else if selected {
+		style += "\x1b[4m"
+	}
+	if style == "" {
+		return line
+	}
+	return style + line + ansiReset
+}
+
+// fit truncates or pads text to exactly width columns
+func fit(text string, width int) string {
+	text = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, text)
+	n := utf8.RuneCountInString(text)
+	if n > width {
+		runes := []rune(text)
+		return string(runes[:max(width-1, 0)]) + "…"
+	}
+	return text + strings.Repeat(" ", width-n)
+}
+
+// scrollTo adjusts the first visible row so that the selected row is visible
+func scrollTo(top, selected, height int) int {
+	if selected < top {
+		return selected
+	}
+	if selected >= top+height {
+		return selected - height + 1
+	}
+	return top
+}
+
+func clamp(value, low, high int) int {
+	if high < low {
+		return low
+	}
+	return min(max(value, low), high)
+}